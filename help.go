@@ -24,6 +24,58 @@ EXAMPLE USAGE:
   
   Register a new acme-dns account for domain example.org, allow updates only from 198.51.100.0/24:
     acme-dns-client register -d example.org -allow 198.51.100.0/24
+
+  Register a new acme-dns account for domain example.org, print the suggested DNS records as a Route53 change batch:
+    acme-dns-client register -d example.org -format route53-changebatch
+
+  Register a new acme-dns account for domain example.org, create the CNAME and CAA records with a dynamic update:
+    acme-dns-client register -d example.org -tsig-key /etc/bind/acme-dns-client.key -update-caa
+
+  Register a new acme-dns account for domain example.org, create the CNAME record using Cloudflare API:
+    CLOUDFLARE_API_TOKEN=... acme-dns-client register -d example.org -provider cloudflare
+
+  Register a new acme-dns account for domain example.org, and store a note and tags with it:
+    acme-dns-client register -d example.org -note "Mail server certificate" -tags mail,production
+`,
+		"show": `
+EXAMPLE USAGE:
+  Show the acme-dns account and its metadata for domain example.org:
+    acme-dns-client show -d example.org
+
+  Show the acme-dns account for domain example.org as JSON:
+    acme-dns-client show -d example.org -json
+`,
+		"list": `
+EXAMPLE USAGE:
+  List all the acme-dns accounts with their metadata and CNAME status as JSON:
+    acme-dns-client list -json
+
+  List all the acme-dns accounts, and flag the ones not validated in the last 60 days as stale:
+    acme-dns-client list -stale-days 60
+`,
+		"update": `
+EXAMPLE USAGE:
+  Update the TXT record of the acme-dns account registered for domain example.org:
+    acme-dns-client update -d example.org -t 'gfj9Xq...Rg85nM'
+
+  Update the TXT records for all the domain and token pairs read from stdin, and wait for them to propagate:
+    printf 'example.org token1\nexample.com token2\n' | acme-dns-client update -batch -
+`,
+		"audit": `
+EXAMPLE USAGE:
+  Show the failed TXT record updates for domain example.org during the last week:
+    acme-dns-client audit -d example.org -action txt_update -result failure -since 168h
+
+  Verify that the audit log has not been tampered with:
+    acme-dns-client audit -verify
+`,
+		"records": `
+EXAMPLE USAGE:
+  Print the CNAME and CAA records for domain example.org in BIND zone file format:
+    acme-dns-client records -d example.org
+
+  Print the CNAME and CAA records for domain example.org as an octoDNS YAML snippet:
+    acme-dns-client records -d example.org -format octodns
 `}
 )
 
@@ -62,6 +114,10 @@ Commands:
   register		Register a new acme-dns account for a domain
   check			Check the configuration and settings of existing acme-dns accounts
   list			List all the existing acme-dns accounts and perform simple CNAME checks for them
+  show			Show an existing acme-dns account and its metadata
+  update		Update the TXT record of an acme-dns account with a validation token
+  audit			Query and verify the audit log of account and TXT record operations
+  records		Print the DNS records for an existing acme-dns account in a format suitable for DNS tooling
 
 Options:
   --help		Print this help text
@@ -85,6 +141,18 @@ EXAMPLE USAGE:
   Check the configuration of all the domains and acme-dns accounts registered on this machine:
     acme-dns-client check
 
+  Check the configuration of example.org using internal resolvers, failing over to the second one:
+    acme-dns-client check -d example.org -ns 10.0.0.53,10.0.1.53
+
+  Check the configuration of example.org, and trace the resolution of the challenge record from the root:
+    acme-dns-client check -d example.org -trace
+
+  Check the configuration of example.org using a DNS-over-HTTPS resolver:
+    acme-dns-client check -d example.org -ns https://cloudflare-dns.com/dns-query
+
+  Print the DNS records for example.org as Terraform resources:
+    acme-dns-client records -d example.org -format terraform
+
   Print help for a "register" command:
     acme-dns-client register --help
 
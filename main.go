@@ -6,8 +6,13 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/acme-dns/acme-dns-client/pkg/client"
+	"github.com/acme-dns/acme-dns-client/pkg/dnsprovider"
+	"github.com/acme-dns/acme-dns-client/pkg/logging"
+	"github.com/acme-dns/acme-dns-client/pkg/records"
+	"github.com/acme-dns/acme-dns-client/pkg/storage"
 )
 
 const (
@@ -19,49 +24,155 @@ func main() {
 	conf := client.NewAcmednsConfig()
 	flag.Usage = UsageGeneric
 
+	// outputFlags adds the output control flags shared by all the commands
+	outputFlags := func(fs *flag.FlagSet) {
+		fs.BoolVar(&conf.Quiet, "q", false, "Quiet mode, only print warnings, errors and the requested data")
+		fs.StringVar(&conf.Color, "color", client.COLOR_AUTO, "Colored output (auto|always|never), auto honors NO_COLOR")
+	}
+
 	checkFlags := flag.NewFlagSet("check", flag.ExitOnError)
 	checkFlags.BoolVar(&conf.Verbose, "v", false, "Verbose output")
 	checkFlags.BoolVar(&conf.Debug, "vv", false, "Very verbose (DEBUG) output")
-	checkFlags.StringVar(&conf.DNSServer, "ns", "1.1.1.1:53", "Fallback DNS server and port to use for lookups")
+	checkFlags.Var((*serverList)(&conf.DNSServers), "ns",
+		"DNS resolver to use for lookups, can be repeated or comma separated for failover. Use https:// URLs for "+
+			"DNS-over-HTTPS and tls://host[:port] for DNS-over-TLS (default: from /etc/resolv.conf)")
+	checkFlags.StringVar(&conf.NSCAFile, "ns-ca", "", "CA certificates (PEM) to verify the DNS-over-HTTPS and DNS-over-TLS resolvers with")
+	checkFlags.BoolVar(&conf.NSInsecure, "ns-insecure", false, "Do not verify the certificates of DNS-over-HTTPS and DNS-over-TLS resolvers")
 	checkFlags.StringVar(&conf.Domain, "d", "", "Target domain name")
+	checkFlags.StringVar(&conf.RecordFormat, "format", "bind", "Format of the suggested DNS records ("+strings.Join(records.Formats, "|")+")")
 
+	checkFlags.IntVar(&conf.Workers, "workers", 10, "Number of domains to check concurrently")
+	checkFlags.DurationVar(&conf.QueryTimeout, "timeout", 0, "Timeout of a single DNS query, 0 uses the timeout from /etc/resolv.conf")
+	checkFlags.IntVar(&conf.QueryRetries, "retries", -1, "Number of retries after a DNS query times out, -1 uses the attempts from /etc/resolv.conf")
+	checkFlags.BoolVar(&conf.Trace, "trace", false,
+		"Trace the resolution of the challenge TXT record iteratively from the root name servers, without recursive resolvers")
+	outputFlags(checkFlags)
 	checkFlags.Usage = FSUsage(checkFlags)
 
 	registerFlags := flag.NewFlagSet("register", flag.ExitOnError)
 	registerFlags.BoolVar(&conf.Dangerous, "dangerous", false, "Acknowledgement that this is a dangerous action")
 	registerFlags.BoolVar(&conf.Verbose, "v", false, "Verbose output")
 	registerFlags.BoolVar(&conf.Debug, "vv", false, "Very verbose (DEBUG) output")
-	registerFlags.StringVar(&conf.DNSServer, "ns", "1.1.1.1:53", "Fallback DNS server and port to use for lookups")
+	registerFlags.Var((*serverList)(&conf.DNSServers), "ns",
+		"DNS resolver to use for lookups, can be repeated or comma separated for failover. Use https:// URLs for "+
+			"DNS-over-HTTPS and tls://host[:port] for DNS-over-TLS (default: from /etc/resolv.conf)")
+	registerFlags.StringVar(&conf.NSCAFile, "ns-ca", "", "CA certificates (PEM) to verify the DNS-over-HTTPS and DNS-over-TLS resolvers with")
+	registerFlags.BoolVar(&conf.NSInsecure, "ns-insecure", false, "Do not verify the certificates of DNS-over-HTTPS and DNS-over-TLS resolvers")
 	registerFlags.StringVar(&conf.Domain, "d", "", "Target domain name")
 	registerFlags.StringVar(&conf.Server, "s",
 		"https://auth.acme-dns.io", "Acme-dns server instance to use")
 	registerFlags.StringVar(&conf.AllowList, "allow", "",
 		"Comma separated allowlist of CIDR masks that are allowed use this acme-dns account. (Default: allow from all)")
+	registerFlags.StringVar(&conf.RecordFormat, "format", "bind", "Format of the suggested DNS records ("+strings.Join(records.Formats, "|")+")")
+	registerFlags.StringVar(&conf.TSIGKeyFile, "tsig-key", "",
+		"TSIG key file used to create the DNS records with RFC 2136 dynamic updates")
+	registerFlags.StringVar(&conf.UpdateServer, "update-server", "",
+		"Name server and port to send the dynamic updates to. (Default: primary name server from the SOA record)")
+	registerFlags.StringVar(&conf.Provider, "provider", "",
+		"DNS provider API used to create the DNS records ("+strings.Join(dnsprovider.Names(), "|")+")")
+	registerFlags.StringVar(&conf.ProviderConfig, "provider-config", "",
+		"JSON file with the DNS provider credentials. (Default: read from environment variables)")
+	registerFlags.BoolVar(&conf.UpdateCAA, "update-caa", false, "Create the CAA records with the DNS provider or dynamic updates as well")
+	registerFlags.BoolVar(&conf.DryRun, "dry-run", false, "Print the DNS changes instead of sending them")
+	registerFlags.StringVar(&conf.Notes, "note", "", "Free form note to store with the account")
+	registerFlags.StringVar(&conf.Tags, "tags", "", "Comma separated list of tags to store with the account")
 
+	outputFlags(registerFlags)
 	registerFlags.Usage = FSUsage(registerFlags)
 
 	listFlags := flag.NewFlagSet("list", flag.ExitOnError)
 	listFlags.BoolVar(&conf.Verbose, "v", false, "Verbose output")
 	listFlags.BoolVar(&conf.Debug, "vv", false, "Very verbose (DEBUG) output")
-	listFlags.StringVar(&conf.DNSServer, "ns", "1.1.1.1:53", "Fallback DNS server and port to use for lookups")
+	listFlags.Var((*serverList)(&conf.DNSServers), "ns",
+		"DNS resolver to use for lookups, can be repeated or comma separated for failover. Use https:// URLs for "+
+			"DNS-over-HTTPS and tls://host[:port] for DNS-over-TLS (default: from /etc/resolv.conf)")
+	listFlags.StringVar(&conf.NSCAFile, "ns-ca", "", "CA certificates (PEM) to verify the DNS-over-HTTPS and DNS-over-TLS resolvers with")
+	listFlags.BoolVar(&conf.NSInsecure, "ns-insecure", false, "Do not verify the certificates of DNS-over-HTTPS and DNS-over-TLS resolvers")
+	listFlags.BoolVar(&conf.JSON, "json", false, "Output the accounts as JSON")
+	listFlags.IntVar(&conf.StaleDays, "stale-days", 90,
+		"Flag accounts without a successful validation in this many days as stale, 0 to disable")
 
+	listFlags.IntVar(&conf.Workers, "workers", 10, "Number of domains to check concurrently")
+	listFlags.DurationVar(&conf.QueryTimeout, "timeout", 0, "Timeout of a single DNS query, 0 uses the timeout from /etc/resolv.conf")
+	listFlags.IntVar(&conf.QueryRetries, "retries", -1, "Number of retries after a DNS query times out, -1 uses the attempts from /etc/resolv.conf")
+	outputFlags(listFlags)
 	listFlags.Usage = FSUsage(listFlags)
 
+	showFlags := flag.NewFlagSet("show", flag.ExitOnError)
+	showFlags.BoolVar(&conf.Verbose, "v", false, "Verbose output, includes the account credentials")
+	showFlags.BoolVar(&conf.Debug, "vv", false, "Very verbose (DEBUG) output")
+	showFlags.StringVar(&conf.Domain, "d", "", "Target domain name")
+	showFlags.BoolVar(&conf.JSON, "json", false, "Output the account as JSON")
+
+	outputFlags(showFlags)
+	showFlags.Usage = FSUsage(showFlags)
+
+	auditFlags := flag.NewFlagSet("audit", flag.ExitOnError)
+	auditFlags.BoolVar(&conf.Verbose, "v", false, "Verbose output")
+	auditFlags.BoolVar(&conf.Debug, "vv", false, "Very verbose (DEBUG) output")
+	auditFlags.StringVar(&conf.Domain, "d", "", "Only show entries for the domain")
+	auditFlags.StringVar(&conf.AuditAction, "action", "", "Only show entries for the action (register|txt_update)")
+	auditFlags.StringVar(&conf.AuditResult, "result", "", "Only show entries with the result (success|failure)")
+	auditFlags.StringVar(&conf.AuditSince, "since", "", "Only show entries since the date, timestamp or duration ago")
+	auditFlags.StringVar(&conf.AuditUntil, "until", "", "Only show entries until the date, timestamp or duration ago")
+	auditFlags.BoolVar(&conf.AuditVerify, "verify", false, "Verify the hash chain of the audit log")
+	auditFlags.BoolVar(&conf.JSON, "json", false, "Output the entries as JSON")
+
+	outputFlags(auditFlags)
+	auditFlags.Usage = FSUsage(auditFlags)
+
+	recordsFlags := flag.NewFlagSet("records", flag.ExitOnError)
+	recordsFlags.BoolVar(&conf.Verbose, "v", false, "Verbose output")
+	recordsFlags.BoolVar(&conf.Debug, "vv", false, "Very verbose (DEBUG) output")
+	recordsFlags.StringVar(&conf.Domain, "d", "", "Target domain name")
+	recordsFlags.StringVar(&conf.RecordFormat, "format", "bind", "Format of the DNS records ("+strings.Join(records.Formats, "|")+")")
+
+	outputFlags(recordsFlags)
+	recordsFlags.Usage = FSUsage(recordsFlags)
+
+	updateFlags := flag.NewFlagSet("update", flag.ExitOnError)
+	updateFlags.BoolVar(&conf.Verbose, "v", false, "Verbose output")
+	updateFlags.BoolVar(&conf.Debug, "vv", false, "Very verbose (DEBUG) output")
+	updateFlags.StringVar(&conf.Domain, "d", "", "Target domain name")
+	updateFlags.StringVar(&conf.Token, "t", "", "Validation token to set as the TXT record value")
+	updateFlags.StringVar(&conf.Batch, "batch", "",
+		"File with domain and token pairs to update at once, one \"domain token\" pair or JSON object per line, or a JSON array. Use - for stdin")
+	updateFlags.IntVar(&conf.Workers, "workers", 4, "Number of concurrent updates in batch mode")
+	updateFlags.DurationVar(&conf.PropagationTimeout, "propagation-timeout", 2*time.Minute,
+		"Time to wait for the TXT records to propagate in batch mode")
+	updateFlags.Var((*serverList)(&conf.DNSServers), "ns",
+		"DNS resolver to use for lookups, can be repeated or comma separated for failover. Use https:// URLs for "+
+			"DNS-over-HTTPS and tls://host[:port] for DNS-over-TLS (default: from /etc/resolv.conf)")
+	updateFlags.StringVar(&conf.NSCAFile, "ns-ca", "", "CA certificates (PEM) to verify the DNS-over-HTTPS and DNS-over-TLS resolvers with")
+	updateFlags.BoolVar(&conf.NSInsecure, "ns-insecure", false, "Do not verify the certificates of DNS-over-HTTPS and DNS-over-TLS resolvers")
+
+	outputFlags(updateFlags)
+	updateFlags.Usage = FSUsage(updateFlags)
+
 	// Server flag for validation
 	flag.StringVar(&conf.Server, "s",
 		"https://auth.acme-dns.io", "Acme-dns server instance to use")
+	flag.DurationVar(&conf.QueueTimeout, "queue-timeout", 10*time.Minute,
+		"Time to wait for pending challenges of the same acme-dns account to be cleaned up")
+	outputFlags(flag.CommandLine)
+	flag.StringVar(&conf.Log, "log", logDefault(),
+		"Logging backend ("+strings.Join(logging.Backends, "|")+"), can be set with ACMEDNS_LOG environment variable as well")
 
 	err := preflight()
 	if err != nil {
-		fmt.Printf("Error while starting up: %s\n", err)
+		fmt.Fprintf(os.Stderr, "Error while starting up: %s\n", err)
 		os.Exit(1)
 	}
 	// Preflight should have ensured that we have the storagepath structure created
 	adnsClient := client.NewAcmednsClient(storagepath)
 	adnsClient.Config = conf
+	if fs, ok := adnsClient.Storage.(*storage.FileStorage); ok && fs.Err() != nil {
+		client.PrintWarning(fmt.Sprintf("%s, account changes will not be saved", fs.Err()), 0)
+	}
 
 	if len(os.Args) < 2 {
 		flag.Parse()
+		initCommand(adnsClient, conf)
 		if !adnsClient.Validation() {
 			UsageGeneric()
 			os.Exit(1)
@@ -73,20 +184,58 @@ func main() {
 	switch os.Args[1] {
 	case "check":
 		checkFlags.Parse(os.Args[2:])
+		initCommand(adnsClient, conf)
+		checkRecordFormat(conf.RecordFormat)
 		// Remove *. as the wildcard CNAME path is the same as the main domains
 		conf.Domain = strings.Replace(conf.Domain, "*.", "", -1)
 		adnsClient.CheckAndPrint()
 	case "register":
 		registerFlags.Parse(os.Args[2:])
+		initCommand(adnsClient, conf)
+		checkRecordFormat(conf.RecordFormat)
 		// Remove *. as the wildcard CNAME path is the same as the main domains
 		conf.Domain = strings.Replace(conf.Domain, "*.", "", -1)
 		adnsClient.Register()
 	case "list":
 		listFlags.Parse(os.Args[2:])
+		initCommand(adnsClient, conf)
 		adnsClient.List()
+	case "show":
+		showFlags.Parse(os.Args[2:])
+		initCommand(adnsClient, conf)
+		conf.Domain = strings.Replace(conf.Domain, "*.", "", -1)
+		if !adnsClient.Show() {
+			os.Exit(1)
+		}
+	case "audit":
+		auditFlags.Parse(os.Args[2:])
+		initCommand(adnsClient, conf)
+		if !adnsClient.Audit() {
+			os.Exit(1)
+		}
+	case "update":
+		updateFlags.Parse(os.Args[2:])
+		initCommand(adnsClient, conf)
+		// Remove *. as the wildcard CNAME path is the same as the main domains
+		conf.Domain = strings.Replace(conf.Domain, "*.", "", -1)
+		if conf.Batch != "" {
+			if !adnsClient.BatchUpdate() {
+				os.Exit(1)
+			}
+		} else if !adnsClient.Update() {
+			os.Exit(1)
+		}
+	case "records":
+		recordsFlags.Parse(os.Args[2:])
+		initCommand(adnsClient, conf)
+		checkRecordFormat(conf.RecordFormat)
+		// Remove *. as the wildcard CNAME path is the same as the main domains
+		conf.Domain = strings.Replace(conf.Domain, "*.", "", -1)
+		adnsClient.Records()
 	default:
 		// This handles --help, -h etc and if found, exits.
 		flag.Parse()
+		initCommand(adnsClient, conf)
 		// We reach this only if no --help etc. was found
 		if !adnsClient.Validation() {
 			UsageGeneric()
@@ -102,3 +251,55 @@ func preflight() error {
 	}
 	return err
 }
+
+// logDefault returns the logging backend set in the environment, or auto
+func logDefault() string {
+	if spec := os.Getenv("ACMEDNS_LOG"); spec != "" {
+		return spec
+	}
+	return "auto"
+}
+
+// initCommand sets up the console output, DNS transport and logging after the command line flags have been parsed
+func initCommand(adnsClient *client.AcmednsClient, conf *client.Config) {
+	err := adnsClient.ConfigureOutput()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+	err = adnsClient.ConfigureDNS()
+	if err != nil {
+		client.PrintError(fmt.Sprintf("%s", err), 0)
+		os.Exit(1)
+	}
+	l, err := logging.New(conf.Log, "acme-dns-client")
+	if err != nil {
+		client.PrintWarning(fmt.Sprintf("Could not set up logging: %s", err), 0)
+		return
+	}
+	client.SetLogger(l)
+}
+
+// serverList is a flag.Value collecting DNS servers from repeated and comma separated values
+type serverList []string
+
+func (s *serverList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *serverList) Set(value string) error {
+	for _, server := range strings.Split(value, ",") {
+		if server = strings.TrimSpace(server); server != "" {
+			*s = append(*s, server)
+		}
+	}
+	return nil
+}
+
+// checkRecordFormat exits with an error if the DNS record format requested by the user is not supported
+func checkRecordFormat(format string) {
+	if !records.ValidFormat(format) {
+		fmt.Fprintf(os.Stderr, "Unknown record format: %s, supported formats: %s\n", format, strings.Join(records.Formats, ", "))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,344 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acme-dns/acme-dns-client/pkg/flock"
+)
+
+const (
+	ACTION_REGISTER   = "register"
+	ACTION_TXT_UPDATE = "txt_update"
+
+	RESULT_SUCCESS = "success"
+	RESULT_FAILURE = "failure"
+)
+
+// Entry is a single audit log record. Each entry includes the hash of the previous one, forming a hash chain
+// that breaks if any of the earlier entries are modified or removed.
+type Entry struct {
+	Time        time.Time `json:"time"`
+	Action      string    `json:"action"`
+	Domain      string    `json:"domain"`
+	SubDomain   string    `json:"subdomain,omitempty"`
+	Server      string    `json:"server,omitempty"`
+	Integration string    `json:"integration,omitempty"`
+	TokenHash   string    `json:"token_hash,omitempty"`
+	Result      string    `json:"result"`
+	Error       string    `json:"error,omitempty"`
+	User        string    `json:"user"`
+	PID         int       `json:"pid"`
+	PrevHash    string    `json:"prev_hash"`
+	Hash        string    `json:"hash"`
+}
+
+// computeHash returns the hash of the entry contents, excluding the hash field itself
+func (e Entry) computeHash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// HashToken returns the hash of a validation token, so that the tokens themselves are not stored in the log
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Log is an append-only JSON lines audit log file. The number of entries and the hash of the last entry are kept
+// in a separate head file next to the log, so that removing entries from the end of the log, or rewriting it
+// without updating the head file, is detected by Verify.
+//
+// The hash chain is not keyed, so it detects accidental corruption and naive edits, but not a deliberate rewrite
+// by someone who can write both the log and the head file. Forward the log to a remote system for that.
+type Log struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewLog returns the audit log for the path. The file is created on the first Append.
+func NewLog(path string) *Log {
+	return &Log{path: path}
+}
+
+// Path returns the path of the audit log file
+func (l *Log) Path() string {
+	return l.path
+}
+
+// head is the state of the log stored in the head file
+type head struct {
+	Count int    `json:"count"`
+	Hash  string `json:"hash"`
+}
+
+func (l *Log) headPath() string {
+	return l.path + ".head"
+}
+
+// readHead returns the contents of the head file, or nil if it does not exist
+func (l *Log) readHead() (*head, error) {
+	data, err := ioutil.ReadFile(l.headPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("Could not read audit log head: %s", err)
+	}
+	h := &head{}
+	if err = json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("Could not parse audit log head %s: %s", l.headPath(), err)
+	}
+	return h, nil
+}
+
+// writeHead atomically replaces the head file
+func (l *Log) writeHead(h head) error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	tmp := l.headPath() + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("Could not write audit log head: %s", err)
+	}
+	return os.Rename(tmp, l.headPath())
+}
+
+// Append fills in the time, user, process and hash chain fields of the entry, and appends it to the log
+func (l *Log) Append(e Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lock, err := flock.Acquire(l.path + ".lock")
+	if err != nil {
+		return fmt.Errorf("Could not lock audit log: %s", err)
+	}
+	defer lock.Release()
+
+	last, err := l.lastEntry()
+	if err != nil {
+		return err
+	}
+	if last != nil {
+		e.PrevHash = last.Hash
+	}
+	h, err := l.readHead()
+	if err != nil {
+		return err
+	}
+	if h == nil {
+		// Logs written before the head file was introduced are counted once
+		entries, err := l.Read()
+		if err != nil {
+			return err
+		}
+		h = &head{Count: len(entries)}
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	e.User = currentUser()
+	e.PID = os.Getpid()
+	e.Hash, err = e.computeHash()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("Could not open audit log: %s", err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("Could not write audit log: %s", err)
+	}
+	return l.writeHead(head{Count: h.Count + 1, Hash: e.Hash})
+}
+
+// lastEntry returns the last entry of the log, or nil if the log is empty
+func (l *Log) lastEntry() (*Entry, error) {
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("Could not open audit log: %s", err)
+	}
+	defer f.Close()
+	line, err := lastLine(f)
+	if err != nil || len(line) == 0 {
+		return nil, err
+	}
+	e := &Entry{}
+	err = json.Unmarshal(line, e)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse the last audit log entry: %s", err)
+	}
+	return e, nil
+}
+
+// lastLine reads the last non-empty line of the file backwards in chunks, to avoid reading the whole log
+func lastLine(f *os.File) ([]byte, error) {
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	const chunk = 4096
+	end := st.Size()
+	buf := make([]byte, 0)
+	for end > 0 {
+		start := end - chunk
+		if start < 0 {
+			start = 0
+		}
+		part := make([]byte, end-start)
+		_, err = f.ReadAt(part, start)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		buf = append(part, buf...)
+		trimmed := bytes.TrimRight(buf, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+		end = start
+	}
+	return bytes.TrimRight(buf, "\n"), nil
+}
+
+// Read returns all the entries of the log
+func (l *Log) Read() ([]Entry, error) {
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return []Entry{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("Could not open audit log: %s", err)
+	}
+	defer f.Close()
+	entries := make([]Entry, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		e := Entry{}
+		err = json.Unmarshal([]byte(line), &e)
+		if err != nil {
+			return entries, fmt.Errorf("Could not parse audit log line %d: %s", lineno, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Verify checks the hash chain of the entries read from the log, and that the log still ends at the entry
+// recorded in the head file. Returns an error describing the first problem found.
+func (l *Log) Verify(entries []Entry) error {
+	if err := Verify(entries); err != nil {
+		return err
+	}
+	h, err := l.readHead()
+	if err != nil {
+		return err
+	}
+	if h == nil {
+		if len(entries) > 0 {
+			return fmt.Errorf("Audit log head %s is missing, the log cannot be checked for removed entries", l.headPath())
+		}
+		return nil
+	}
+	last := ""
+	if len(entries) > 0 {
+		last = entries[len(entries)-1].Hash
+	}
+	if h.Count != len(entries) || h.Hash != last {
+		return fmt.Errorf("Audit log has %d entries but %d were written, entries have been removed from the end or the log has been rewritten",
+			len(entries), h.Count)
+	}
+	return nil
+}
+
+// Verify checks the hash chain of the entries, and returns an error describing the first broken link
+func Verify(entries []Entry) error {
+	prev := ""
+	for i, e := range entries {
+		if e.PrevHash != prev {
+			return fmt.Errorf("Entry %d (%s) does not link to the previous entry, entries have been removed or reordered",
+				i+1, e.Time.Format(time.RFC3339))
+		}
+		hash, err := e.computeHash()
+		if err != nil {
+			return err
+		}
+		if hash != e.Hash {
+			return fmt.Errorf("Entry %d (%s) has been modified", i+1, e.Time.Format(time.RFC3339))
+		}
+		prev = e.Hash
+	}
+	return nil
+}
+
+// Filter selects audit log entries. Empty fields match all entries.
+type Filter struct {
+	Domain string
+	Action string
+	Result string
+	Since  time.Time
+	Until  time.Time
+}
+
+// Match returns true if the entry matches the filter
+func (f Filter) Match(e Entry) bool {
+	if f.Domain != "" && !strings.EqualFold(strings.TrimSuffix(f.Domain, "."), strings.TrimSuffix(e.Domain, ".")) {
+		return false
+	}
+	if f.Action != "" && f.Action != e.Action {
+		return false
+	}
+	if f.Result != "" && f.Result != e.Result {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+func currentUser() string {
+	u, err := user.Current()
+	if err == nil && u.Username != "" {
+		return u.Username
+	}
+	return strconv.Itoa(os.Getuid())
+}
@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestLog returns a log with the number of entries in a temporary directory removed after the test
+func newTestLog(t *testing.T, count int) *Log {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "acmedns-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	l := NewLog(filepath.Join(dir, "audit.log"))
+	for i := 0; i < count; i++ {
+		e := Entry{Action: ACTION_TXT_UPDATE, Domain: "example.org", TokenHash: HashToken(string(rune('a' + i))), Result: RESULT_SUCCESS}
+		if err := l.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return l
+}
+
+// readLines returns the lines of the log file
+func readLines(t *testing.T, l *Log) []string {
+	t.Helper()
+	data, err := ioutil.ReadFile(l.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func writeLines(t *testing.T, l *Log, lines []string) {
+	t.Helper()
+	data := strings.Join(lines, "\n")
+	if len(lines) > 0 {
+		data += "\n"
+	}
+	if err := ioutil.WriteFile(l.Path(), []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAppendChainsEntries(t *testing.T) {
+	l := newTestLog(t, 3)
+	entries, err := l.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	if entries[0].PrevHash != "" || entries[1].PrevHash != entries[0].Hash || entries[2].PrevHash != entries[1].Hash {
+		t.Error("Expected each entry to link to the previous one")
+	}
+	if entries[0].User == "" || entries[0].PID == 0 || entries[0].Time.IsZero() {
+		t.Errorf("Expected the user, process and time to be filled in, got %v", entries[0])
+	}
+	if err = l.Verify(entries); err != nil {
+		t.Errorf("Expected the log to verify, got: %s", err)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		tamper func(t *testing.T, l *Log)
+		err    string
+	}{
+		{"modified middle entry", func(t *testing.T, l *Log) {
+			lines := readLines(t, l)
+			lines[1] = strings.Replace(lines[1], `"result":"success"`, `"result":"failure"`, 1)
+			writeLines(t, l, lines)
+		}, "has been modified"},
+		{"removed middle entry", func(t *testing.T, l *Log) {
+			lines := readLines(t, l)
+			writeLines(t, l, append(lines[:1], lines[2:]...))
+		}, "does not link to the previous entry"},
+		{"reordered entries", func(t *testing.T, l *Log) {
+			lines := readLines(t, l)
+			lines[1], lines[2] = lines[2], lines[1]
+			writeLines(t, l, lines)
+		}, "does not link to the previous entry"},
+		{"truncated log", func(t *testing.T, l *Log) {
+			lines := readLines(t, l)
+			writeLines(t, l, lines[:2])
+		}, "has 2 entries but 3 were written"},
+		{"emptied log", func(t *testing.T, l *Log) {
+			writeLines(t, l, nil)
+		}, "has 0 entries but 3 were written"},
+		{"missing head", func(t *testing.T, l *Log) {
+			os.Remove(l.headPath())
+		}, "head"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			l := newTestLog(t, 3)
+			tc.tamper(t, l)
+			entries, err := l.Read()
+			if err == nil {
+				err = l.Verify(entries)
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Expected an error containing %q, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestAppendContinuesLegacyLog(t *testing.T) {
+	l := newTestLog(t, 2)
+	// Logs written before the head file was introduced do not have one
+	os.Remove(l.headPath())
+	if err := l.Append(Entry{Action: ACTION_REGISTER, Domain: "example.org", Result: RESULT_SUCCESS}); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := l.Read()
+	if err := l.Verify(entries); err != nil {
+		t.Errorf("Expected the log to verify after the head file was created, got: %s", err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	l := newTestLog(t, 1)
+	entries, _ := l.Read()
+	e := entries[0]
+	for _, tc := range []struct {
+		filter Filter
+		match  bool
+	}{
+		{Filter{}, true},
+		{Filter{Domain: "Example.org."}, true},
+		{Filter{Domain: "example.com"}, false},
+		{Filter{Action: ACTION_TXT_UPDATE, Result: RESULT_SUCCESS}, true},
+		{Filter{Action: ACTION_REGISTER}, false},
+		{Filter{Result: RESULT_FAILURE}, false},
+		{Filter{Since: e.Time.Add(-1)}, true},
+		{Filter{Since: e.Time.Add(1)}, false},
+		{Filter{Until: e.Time.Add(-1)}, false},
+	} {
+		if match := tc.filter.Match(e); match != tc.match {
+			t.Errorf("Filter %+v: expected match %t, got %t", tc.filter, tc.match, match)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/acme-dns/acme-dns-client/pkg/audit"
+
+	"github.com/cpu/goacmedns"
+)
+
+// auditRegister records an acme-dns account registration attempt in the audit log
+func (c *AcmednsClient) auditRegister(domain string, acct goacmedns.Account, opErr error) {
+	c.appendAudit(audit.Entry{
+		Action:    audit.ACTION_REGISTER,
+		Domain:    domain,
+		SubDomain: acct.SubDomain,
+		Server:    c.Config.Server,
+	}, opErr)
+}
+
+// auditTXTUpdate records a TXT record update attempt in the audit log. Only the hash of the token is stored.
+func (c *AcmednsClient) auditTXTUpdate(domain string, acct goacmedns.Account, token string, opErr error) {
+	c.appendAudit(audit.Entry{
+		Action:    audit.ACTION_TXT_UPDATE,
+		Domain:    domain,
+		SubDomain: acct.SubDomain,
+		Server:    acct.ServerURL,
+		TokenHash: audit.HashToken(token),
+	}, opErr)
+}
+
+func (c *AcmednsClient) appendAudit(e audit.Entry, opErr error) {
+	if c.AuditLog == nil {
+		return
+	}
+	e.Integration = c.caller
+	e.Result = audit.RESULT_SUCCESS
+	if opErr != nil {
+		e.Result = audit.RESULT_FAILURE
+		e.Error = opErr.Error()
+	}
+	err := c.AuditLog.Append(e)
+	if err != nil {
+		PrintWarning(fmt.Sprintf("Could not write to audit log %s: %s", c.AuditLog.Path(), err), 0)
+	}
+}
+
+// Audit prints the audit log entries matching the configured filters, and optionally verifies the hash chain
+func (c *AcmednsClient) Audit() bool {
+	filter := audit.Filter{
+		Domain: c.Config.Domain,
+		Action: c.Config.AuditAction,
+		Result: c.Config.AuditResult,
+	}
+	var err error
+	if filter.Since, err = parseAuditTime(c.Config.AuditSince); err != nil {
+		PrintError(fmt.Sprintf("Invalid -since value: %s", err), 0)
+		return false
+	}
+	if filter.Until, err = parseAuditTime(c.Config.AuditUntil); err != nil {
+		PrintError(fmt.Sprintf("Invalid -until value: %s", err), 0)
+		return false
+	}
+	entries, err := c.AuditLog.Read()
+	if err != nil {
+		PrintError(fmt.Sprintf("%s", err), 0)
+		return false
+	}
+	if c.Config.AuditVerify {
+		err = c.AuditLog.Verify(entries)
+		if err != nil {
+			PrintError(fmt.Sprintf("Audit log verification failed: %s", err), 0)
+			return false
+		}
+		PrintSuccess(fmt.Sprintf("Audit log hash chain of %d entries verified", len(entries)), 0)
+		return true
+	}
+
+	matching := make([]audit.Entry, 0)
+	for _, e := range entries {
+		if filter.Match(e) {
+			matching = append(matching, e)
+		}
+	}
+	if c.Config.JSON {
+		printJSON(matching)
+		return true
+	}
+	for _, e := range matching {
+		line := fmt.Sprintf("%s  %-10s %-8s %s", e.Time.Local().Format("2006-01-02 15:04:05"), e.Action, e.Result, e.Domain)
+		details := make([]string, 0)
+		if e.SubDomain != "" {
+			details = append(details, "subdomain "+e.SubDomain)
+		}
+		if e.Integration != "" {
+			details = append(details, "by "+e.Integration)
+		}
+		details = append(details, fmt.Sprintf("user %s, pid %d", e.User, e.PID))
+		if e.Error != "" {
+			details = append(details, "error: "+e.Error)
+		}
+		PrintData("%s (%s)\n", line, strings.Join(details, ", "))
+	}
+	return true
+}
+
+// parseAuditTime parses a date, an RFC 3339 timestamp or a duration relative to the current time
+func parseAuditTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return t, fmt.Errorf("expected a date (2006-01-02), a timestamp or a duration (eg. 72h)")
+	}
+	return t, nil
+}
@@ -0,0 +1,214 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/acme-dns/acme-dns-client/pkg/integration"
+
+	"github.com/cpu/goacmedns"
+)
+
+// acme-dns keeps only the two most recent TXT records for each account
+const maxTokensPerAccount = 2
+
+type batchRequest struct {
+	Domain string `json:"domain"`
+	Token  string `json:"token"`
+}
+
+type batchResult struct {
+	Request    integration.ValidationRequest
+	Account    goacmedns.Account
+	Err        error
+	Propagated bool
+	// Original is the index of the earlier request with the same token for the same acme-dns account, or -1
+	Original int
+}
+
+// BatchUpdate updates the TXT records for all the domain and token pairs read from the batch file, or stdin
+// if the filename is "-". The updates are done concurrently, after which the propagation of all of them is
+// awaited together.
+func (c *AcmednsClient) BatchUpdate() bool {
+	c.caller = "batch"
+	var input io.Reader = os.Stdin
+	if c.Config.Batch != "-" {
+		f, err := os.Open(c.Config.Batch)
+		if err != nil {
+			PrintError(fmt.Sprintf("Could not open batch file: %s", err), 0)
+			return false
+		}
+		defer f.Close()
+		input = f
+	}
+	reqs, err := parseBatch(input)
+	if err != nil {
+		PrintError(fmt.Sprintf("Could not parse batch input: %s", err), 0)
+		return false
+	}
+	if len(reqs) == 0 {
+		PrintWarning("No updates found in the batch input", 0)
+		return true
+	}
+
+	results := make([]batchResult, len(reqs))
+	tokens := make(map[string][]string)
+	seen := make(map[[2]string]int)
+	for i, r := range reqs {
+		results[i].Request = r
+		results[i].Original = -1
+		results[i].Account, results[i].Err = c.accountForValidation(r.Domain)
+		if results[i].Err == nil {
+			fd := results[i].Account.FullDomain
+			// The same token for the same account (e.g. a domain and its wildcard) needs only a single TXT record
+			if orig, ok := seen[[2]string{fd, r.Token}]; ok {
+				results[i].Original = orig
+				continue
+			}
+			seen[[2]string{fd, r.Token}] = i
+			tokens[fd] = append(tokens[fd], r.Token)
+		}
+	}
+	for fd, t := range tokens {
+		if len(t) > maxTokensPerAccount {
+			PrintError(fmt.Sprintf("Batch contains %d tokens for acme-dns account %s, but acme-dns keeps only %d TXT records per account. Refusing to update.",
+				len(t), fd, maxTokensPerAccount), 0)
+			return false
+		}
+	}
+
+	c.runBatchUpdates(results)
+	c.awaitPropagation(results)
+	return printBatchResults(results)
+}
+
+// runBatchUpdates performs the updates using a bounded pool of workers. Like in hook mode, each update waits for
+// a free TXT record slot in the challenge queue of the acme-dns account, and the slot stays reserved until the
+// challenge times out. Duplicate requests share the reservation and the update of the original request, as
+// sending them again would push the other token of the account out of acme-dns.
+func (c *AcmednsClient) runBatchUpdates(results []batchResult) {
+	c.forEach(len(results), func(i int) {
+		r := &results[i]
+		if r.Err == nil && r.Original < 0 {
+			c.reserveChallenge(r.Account, r.Request.Domain, r.Request.Token)
+			_, r.Err = c.updateTXT(r.Request.Domain, r.Request.Token)
+			if r.Err != nil {
+				c.releaseChallenge(r.Request.Domain, r.Request.Token)
+			}
+		}
+	})
+	for i := range results {
+		if orig := results[i].Original; orig >= 0 {
+			results[i].Err = results[orig].Err
+		}
+	}
+}
+
+// awaitPropagation polls the authoritative name servers of the acme-dns accounts until all the updated
+// tokens are visible, or the propagation timeout is reached
+func (c *AcmednsClient) awaitPropagation(results []batchResult) {
+	dnsc := c.dnsClient()
+	deadline := time.Now().Add(c.Config.PropagationTimeout)
+	for {
+		pending := 0
+		for i := range results {
+			r := &results[i]
+			if r.Err != nil || r.Propagated {
+				continue
+			}
+			values, err := dnsc.GetTXT(r.Account.FullDomain)
+			if err != nil {
+				c.Debug(fmt.Sprintf("TXT query for %s: %s", r.Account.FullDomain, err))
+			}
+			for _, v := range values {
+				if v == r.Request.Token {
+					r.Propagated = true
+				}
+			}
+			if !r.Propagated {
+				pending++
+			}
+		}
+		if pending == 0 || time.Now().After(deadline) {
+			return
+		}
+		c.Verbose(fmt.Sprintf("Waiting for %d TXT record(s) to propagate", pending))
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// printBatchResults prints out the per domain results, and returns true if all of them were successful
+func printBatchResults(results []batchResult) bool {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Request.Domain < results[j].Request.Domain
+	})
+	success := true
+	for _, r := range results {
+		if r.Err != nil {
+			PrintError(fmt.Sprintf("%s: %s", r.Request.Domain, r.Err), 0)
+			success = false
+		} else if !r.Propagated {
+			PrintWarning(fmt.Sprintf("%s: updated, but the TXT record did not propagate in time", r.Request.Domain), 0)
+			success = false
+		} else {
+			PrintSuccess(fmt.Sprintf("%s: updated and propagated", r.Request.Domain), 0)
+		}
+	}
+	return success
+}
+
+// parseBatch parses the batch input. Accepted formats are a JSON array of {"domain": ..., "token": ...}
+// objects, one such JSON object per line, or whitespace separated "domain token" pairs per line.
+func parseBatch(input io.Reader) ([]integration.ValidationRequest, error) {
+	reqs := make([]integration.ValidationRequest, 0)
+	data, err := ioutil.ReadAll(input)
+	if err != nil {
+		return reqs, err
+	}
+	data = bytes.TrimSpace(data)
+	batch := make([]batchRequest, 0)
+	if bytes.HasPrefix(data, []byte("[")) {
+		err = json.Unmarshal(data, &batch)
+		if err != nil {
+			return reqs, err
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		lineno := 0
+		for scanner.Scan() {
+			lineno++
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if strings.HasPrefix(line, "{") {
+				br := batchRequest{}
+				if err = json.Unmarshal([]byte(line), &br); err != nil {
+					return reqs, fmt.Errorf("line %d: %s", lineno, err)
+				}
+				batch = append(batch, br)
+				continue
+			}
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				return reqs, fmt.Errorf("line %d: expected \"domain token\"", lineno)
+			}
+			batch = append(batch, batchRequest{Domain: fields[0], Token: fields[1]})
+		}
+	}
+	for i, br := range batch {
+		if br.Domain == "" || br.Token == "" {
+			return reqs, fmt.Errorf("entry %d: both domain and token are required", i+1)
+		}
+		reqs = append(reqs, integration.ValidationRequest{Domain: strings.TrimPrefix(br.Domain, "*."), Token: br.Token})
+	}
+	return reqs, nil
+}
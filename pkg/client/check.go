@@ -2,11 +2,14 @@ package client
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/acme-dns/acme-dns-client/pkg/dnsclient"
+	"github.com/acme-dns/acme-dns-client/pkg/records"
 
 	"github.com/cpu/goacmedns"
+	"github.com/miekg/dns"
 )
 
 type ConfigurationState struct {
@@ -14,6 +17,13 @@ type ConfigurationState struct {
 	Account goacmedns.Account
 	CNAME dnsclient.CNAMERecord
 	CAA []dnsclient.CAARecord
+	// Delegation is the NS delegation of the challenge domain, used instead of a CNAME record
+	Delegation dnsclient.Delegation
+	// Conflicts are the records at the challenge domain breaking or obscuring its delegation
+	Conflicts []dnsclient.Conflict
+	// Trace is the iterative resolution of the challenge TXT record, if requested
+	Trace *dnsclient.Trace
+	TraceErr error
 }
 
 func NewConfigurationState(domain string) ConfigurationState {
@@ -22,6 +32,7 @@ func NewConfigurationState(domain string) ConfigurationState {
 		Account: goacmedns.Account{},
 		CNAME: dnsclient.CNAMERecord{},
 		CAA: make([]dnsclient.CAARecord, 0),
+		Conflicts: make([]dnsclient.Conflict, 0),
 	}
 }
 
@@ -39,15 +50,20 @@ func (c *AcmednsClient) CheckAndPrint() {
 			domains = append(domains, d)
 		}
 	}
-	for _, d := range domains {
-		// Perform the check for each domain listed
-		c.checkAndPrint(c.ConfigurationState(d))
+	sort.Strings(domains)
+	// Gather the configuration states concurrently, and print them in the sorted order of the domains
+	states := make([]ConfigurationState, len(domains))
+	c.forEach(len(domains), func(i int) {
+		states[i] = c.ConfigurationState(domains[i])
+	})
+	for _, cstate := range states {
+		c.checkAndPrint(cstate)
 	}
 }
 
 func (c *AcmednsClient) ConfigurationState(domain string) ConfigurationState {
 	cstate := NewConfigurationState(domain)
-	dnsc := dnsclient.NewDNSClient(c.Config.DNSServer)
+	dnsc := c.dnsClient()
 	var err error
 
 	// Populate CNAME record information
@@ -56,17 +72,35 @@ func (c *AcmednsClient) ConfigurationState(domain string) ConfigurationState {
 		c.Verbose(fmt.Sprintf("%s", err))
 	}
 
+	// Populate conflicting record information
+	cstate.Conflicts, err = dnsc.GetConflicts(domain)
+	if err != nil {
+		c.Verbose(fmt.Sprintf("%s", err))
+	}
+
 	// Populate CAA record information
 	cstate.CAA, err = dnsc.GetCAA(domain)
 	if err != nil {
 		c.Verbose(fmt.Sprintf("%s", err))
 	}
 
+	if c.Config.Trace {
+		cstate.Trace, cstate.TraceErr = dnsc.Trace("_acme-challenge."+domain, dns.TypeTXT)
+	}
+
 	// Populate existing acme-dns account information
 	cstate.Account, err = c.acmeDnsAccountForDomain(domain)
 	if err != nil {
 		c.Verbose(fmt.Sprintf("%s", err))
 	}
+
+	// Populate NS delegation information, if there is no CNAME record
+	if !cstate.CNAME.HasCNAME && cstate.HasAcmednsAccount() {
+		cstate.Delegation, err = dnsc.GetDelegation(domain, cstate.Account.FullDomain)
+		if err != nil {
+			c.Verbose(fmt.Sprintf("%s", err))
+		}
+	}
 	return cstate
 }
 
@@ -83,11 +117,16 @@ func (c *AcmednsClient) acmeDnsAccountForDomain(domain string) (goacmedns.Accoun
 }
 
 func (c *AcmednsClient) checkAndPrint(cstate ConfigurationState) {
-	fmt.Printf("Checking acme-dns configuration for domain %s\n", cstate.Domain)
+	Printf("Checking acme-dns configuration for domain %s\n", cstate.Domain)
 	// Check acme-dns account and CNAME records
 	c.PrintAcmednsAccountInfo(cstate)
+	// Check records conflicting with the CNAME record
+	c.PrintConflicts(cstate)
 	// Check CAA records
 	cstate.PrintCAAResults()
+	if cstate.Trace != nil {
+		cstate.PrintTrace()
+	}
 }
 
 func (c *AcmednsClient) PrintAcmednsAccountInfo(cstate ConfigurationState) {
@@ -95,17 +134,25 @@ func (c *AcmednsClient) PrintAcmednsAccountInfo(cstate ConfigurationState) {
 		PrintError("No acme-dns account registered", 1)
 	} else {
 		PrintSuccess("Registered acme-dns account found!",1)
-		if cstate.CNAME.CorrectTarget(cstate.Account.FullDomain) {
+		if cstate.Delegation.IsDelegated() {
+			printDelegation(cstate.Delegation)
+		} else if cstate.CNAME.CorrectTarget(cstate.Account.FullDomain) {
 			PrintSuccess("CNAME record found and set up correctly!", 1)
+			if len(cstate.CNAME.Chain) > 1 {
+				PrintInfo(fmt.Sprintf("CNAME chain: %s", cstate.CNAME.String()), 1)
+			}
 		} else if cstate.CNAME.Target != "" {
 			PrintError(fmt.Sprintf("CNAME record found, but it's pointing to a wrong domain. expected: %s, found: %s",
-				cstate.Account.FullDomain, cstate.CNAME.Target), 1)
-			PrintInfo(fmt.Sprintf(`A correctly set up CNAME record should look like the following:
-    _acme-challenge.%s. 120 IN      CNAME   %s.`, cstate.Domain, cstate.Account.FullDomain), 1)
+				cstate.Account.FullDomain, cstate.CNAME.FinalTarget()), 1)
+			if len(cstate.CNAME.Chain) > 1 {
+				PrintInfo(fmt.Sprintf("CNAME chain: %s", cstate.CNAME.String()), 1)
+			}
+			PrintInfo(fmt.Sprintf("A correctly set up CNAME record should look like the following:\n%s",
+				c.formatRecords(cstate.Domain, []records.Record{records.ChallengeCNAME(cstate.Domain, cstate.Account.FullDomain)})), 1)
 		} else {
 			PrintError(fmt.Sprintf("No CNAME record found"), 1)
-			PrintInfo(fmt.Sprintf(`A correctly set up CNAME record should look like the following:
-    _acme-challenge.%s.    IN      CNAME   %s.`, cstate.Domain, cstate.Account.FullDomain), 1)
+			PrintInfo(fmt.Sprintf("A correctly set up CNAME record should look like the following:\n%s",
+				c.formatRecords(cstate.Domain, []records.Record{records.ChallengeCNAME(cstate.Domain, cstate.Account.FullDomain)})), 1)
 			if YesNoPrompt("Do you want to set up the CNAME record now and have acme-dns-client monitor the change?", false) {
 				_ = c.CNAMESetupWizard(cstate.Domain)
 			}
@@ -113,16 +160,77 @@ func (c *AcmednsClient) PrintAcmednsAccountInfo(cstate ConfigurationState) {
 	}
 }
 
+// printDelegation prints the results of checking the NS delegation of the challenge domain
+func printDelegation(d dnsclient.Delegation) {
+	if d.Working() {
+		PrintSuccess(fmt.Sprintf("%s is delegated to %s, and set up correctly!", d.Name, strings.Join(d.NameServers, ", ")), 1)
+		return
+	}
+	PrintError(fmt.Sprintf("%s is delegated to %s, but the delegation is not working", d.Name, strings.Join(d.NameServers, ", ")), 1)
+	for _, s := range d.Servers {
+		if !s.Working() {
+			PrintError(delegatedServerIssue(d.Name, s), 2)
+		}
+	}
+	PrintInfo("The delegated name servers should be the acme-dns servers, answering for the challenge domain like for the acme-dns account domain. Alternatively, replace the NS records with a CNAME record.", 1)
+}
+
+// delegatedServerIssue describes why the delegated name server is not working
+func delegatedServerIssue(name string, s dnsclient.DelegatedServer) string {
+	switch {
+	case s.Err != nil:
+		return fmt.Sprintf("%s: %s", s.Host, s.Err)
+	case !s.Authoritative:
+		return fmt.Sprintf("%s does not answer authoritatively for %s and the acme-dns account domain", s.Host, name)
+	default:
+		return fmt.Sprintf("%s answers differently for %s and the acme-dns account domain", s.Host, name)
+	}
+}
+
+// PrintConflicts prints the records breaking the delegation of the challenge domain, and how to fix them
+func (c *AcmednsClient) PrintConflicts(cstate ConfigurationState) {
+	for _, conflict := range cstate.Conflicts {
+		finding, fix := describeConflict(conflict)
+		PrintError(finding, 1)
+		PrintInfo(fix, 1)
+	}
+}
+
+// describeConflict returns the description of the conflict and the fix for it
+func describeConflict(conflict dnsclient.Conflict) (string, string) {
+	records := strings.Join(conflict.Records, ", ")
+	switch conflict.Kind {
+	case dnsclient.ConflictTXT:
+		return fmt.Sprintf("TXT record found at %s: %s", conflict.Name, records),
+			"Remove the TXT record. The challenge TXT records are served by acme-dns through the CNAME record, and a TXT record " +
+				"next to a CNAME record is invalid, while some DNS providers serve it instead of following the CNAME."
+	case dnsclient.ConflictAddress:
+		return fmt.Sprintf("%s record found at %s: %s", dns.TypeToString[conflict.Type], conflict.Name, records),
+			fmt.Sprintf("Remove the %s record, a CNAME record cannot coexist with other records of the same name.",
+				dns.TypeToString[conflict.Type])
+	case dnsclient.ConflictNS:
+		return fmt.Sprintf("NS records found at %s: %s", conflict.Name, records),
+			"Remove either the NS records or the CNAME record, a delegated name cannot have a CNAME record."
+	case dnsclient.ConflictWildcard:
+		return fmt.Sprintf("Records of %s are synthesized from the wildcard %s: %s", conflict.Name, conflict.Wildcard, records),
+			"Add an explicit CNAME record for the name, it takes precedence over the wildcard."
+	case dnsclient.ConflictEmptyNonTerminal:
+		return fmt.Sprintf("%s exists without CNAME, TXT, A, AAAA or NS records of its own (an empty non-terminal)", conflict.Name),
+			"Remove the leftover records below the name, and add the CNAME record."
+	}
+	return fmt.Sprintf("Conflicting records found at %s: %s", conflict.Name, records), "Remove the conflicting records."
+}
+
 func (c *ConfigurationState) PrintCAAResults() {
 	if c.HasCAA() {
-		fmt.Printf(" %s CAA record found!\n", successMarker())
+		PrintSuccess("CAA record found!", 1)
 	} else {
-		fmt.Printf(" %s No CAA record found\n", warningMarker())
+		PrintWarning("No CAA record found", 1)
 	}
 	if c.HasAccountURI() {
-		fmt.Printf(" %s CAA AccountURI found!\n", successMarker())
+		PrintSuccess("CAA AccountURI found!", 1)
 	} else {
-		fmt.Printf(" %s No CAA AccountURI found\n", warningMarker())
+		PrintWarning("No CAA AccountURI found", 1)
 	}
 }
 
@@ -151,6 +259,11 @@ func (c *ConfigurationState) HasAcmednsAccount() bool {
 	return false
 }
 
+// CorrectDelegation returns true if the challenge domain is delegated to working acme-dns name servers
+func (c *ConfigurationState) CorrectDelegation() bool {
+	return c.HasAcmednsAccount() && c.Delegation.Working()
+}
+
 func (c *ConfigurationState) CorrectCNAME() bool {
 	if c.HasAcmednsAccount() && c.CNAME.CorrectTarget(c.Account.FullDomain) {
 		return true
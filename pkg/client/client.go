@@ -1,20 +1,73 @@
 package client
 
-import "github.com/cpu/goacmedns"
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/acme-dns/acme-dns-client/pkg/audit"
+	"github.com/acme-dns/acme-dns-client/pkg/dnsclient"
+	"github.com/acme-dns/acme-dns-client/pkg/logging"
+	"github.com/acme-dns/acme-dns-client/pkg/storage"
+)
+
+// STORAGE_BACKUPS is the number of previous versions of the storage file to keep
+const STORAGE_BACKUPS = 5
 
 type AcmednsClient struct {
 	Config *Config
-	Storage goacmedns.Storage
+	Storage storage.Storage
+	// StateDir holds the runtime state shared between acme-dns-client processes
+	StateDir string
+	// AuditLog records the account and TXT record operations
+	AuditLog *audit.Log
+	// Output receives the verbose and debug output of the client
+	Output Sink
+	// dnsCache holds the NS, SOA and address lookups for the duration of the run
+	dnsCache *dnsclient.Cache
+	// dnsTransport holds the connections to the encrypted DNS resolvers for the duration of the run
+	dnsTransport *dnsclient.Transport
+	// caller is the integration or command that requested the current operation, for the audit log
+	caller string
 }
 
 type Config struct {
 	Verbose bool
 	Debug bool
 	Domain string
+	Token string
+	Batch string
+	Workers int
+	PropagationTimeout time.Duration
+	QueueTimeout time.Duration
 	Server string
 	AllowList string
-	DNSServer string
+	DNSServers []string
 	Dangerous bool
+	RecordFormat string
+	TSIGKeyFile string
+	UpdateServer string
+	UpdateCAA bool
+	DryRun bool
+	Provider string
+	ProviderConfig string
+	Notes string
+	Tags string
+	JSON bool
+	StaleDays int
+	AuditVerify bool
+	AuditAction string
+	AuditResult string
+	AuditSince string
+	AuditUntil string
+	Log string
+	Quiet bool
+	Color string
+	QueryTimeout time.Duration
+	QueryRetries int
+	NSCAFile string
+	NSInsecure bool
+	Trace bool
 }
 
 func NewAcmednsConfig() *Config {
@@ -24,24 +77,108 @@ func NewAcmednsConfig() *Config {
 		Domain: "",
 		Server: "",
 		AllowList: "",
+		RecordFormat: "bind",
+		Workers: 4,
+		PropagationTimeout: 2 * time.Minute,
+		QueueTimeout: 10 * time.Minute,
+		StaleDays: 90,
+		Log: "auto",
+		Color: COLOR_AUTO,
+		// Zero timeout and negative retries use the system resolver configuration
+		QueryTimeout: 0,
+		QueryRetries: -1,
 	}
 }
 
 func NewAcmednsClient(storagepath string) *AcmednsClient {
 	return &AcmednsClient{
 		Config: NewAcmednsConfig(),
-		Storage: goacmedns.NewFileStorage(storagepath, 0600),
+		Storage: storage.NewFileStorage(storagepath, 0600, STORAGE_BACKUPS),
+		StateDir: filepath.Dir(storagepath),
+		Output: output,
+		dnsCache: dnsclient.NewCache(),
+		AuditLog: audit.NewLog(filepath.Join(filepath.Dir(storagepath), "audit.log")),
+	}
+}
+
+// dnsClient returns a DNS client using the configured resolvers, timeout and retries, sharing the lookup cache of the run
+func (c *AcmednsClient) dnsClient() *dnsclient.Client {
+	dnsc := dnsclient.NewDNSClient(c.Config.DNSServers)
+	if c.Config.QueryTimeout > 0 {
+		dnsc.Timeout = c.Config.QueryTimeout
+	}
+	if c.Config.QueryRetries >= 0 {
+		dnsc.Retries = c.Config.QueryRetries
 	}
+	dnsc.Cache = c.dnsCache
+	dnsc.Transport = c.dnsTransport
+	return dnsc
+}
+
+// ConfigureDNS sets up the certificate verification and the shared connections for the DNS-over-HTTPS and
+// DNS-over-TLS resolvers
+func (c *AcmednsClient) ConfigureDNS() error {
+	conf, err := dnsclient.NewTLSConfig(c.Config.NSCAFile, c.Config.NSInsecure)
+	if err != nil {
+		return err
+	}
+	c.dnsTransport = dnsclient.NewTransport(conf)
+	return nil
+}
+
+// forEach calls fn for the indexes 0..count-1 using a bounded pool of workers
+func (c *AcmednsClient) forEach(count int, fn func(i int)) {
+	workers := c.Config.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
 }
 
 func (c *AcmednsClient) Debug(input string) {
 	if c.Config.Debug {
-		PrintDebug(input, 0)
+		c.debugOutput(input)
 	}
 }
 
 func (c *AcmednsClient) Verbose(input string) {
 	if c.Config.Verbose || c.Config.Debug {
-		PrintDebug(input, 0)
+		c.debugOutput(input)
+	}
+}
+
+// verboseConsoleOnly works like Verbose, but the message is never forwarded to the logging backend. It is used
+// for secrets, like the acme-dns account password, that must not end up in the system log.
+func (c *AcmednsClient) verboseConsoleOnly(input string) {
+	if c.Config.Verbose || c.Config.Debug {
+		c.consoleOutput(input)
+	}
+}
+
+func (c *AcmednsClient) debugOutput(input string) {
+	logOutput(logging.LevelDebug, input)
+	c.consoleOutput(input)
+}
+
+func (c *AcmednsClient) consoleOutput(input string) {
+	if c.Output == nil {
+		output.Message(OutputDebug, input, 0)
+		return
 	}
+	c.Output.Message(OutputDebug, input, 0)
 }
\ No newline at end of file
@@ -1,12 +0,0 @@
-// +build windows
-
-package client
-
-const (
-	TERMINAL_CLEAR_LINE = "\r\r"
-	ANSI_CLEAR          = ""
-	ANSI_RED            = ""
-	ANSI_GREEN          = ""
-	ANSI_BLUE           = ""
-	ANSI_YELLOW         = ""
-)
\ No newline at end of file
@@ -1,50 +1,187 @@
 package client
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/acme-dns/acme-dns-client/pkg/dnsclient"
+	"github.com/acme-dns/acme-dns-client/pkg/storage"
+
+	"github.com/cpu/goacmedns"
+)
+
+const (
+	ACCOUNT_WORKING       = "working"
+	ACCOUNT_ERROR         = "error"
+	ACCOUNT_DYSFUNCTIONAL = "dysfunctional"
+)
+
+const (
+	// SETUP_CNAME means _acme-challenge is a CNAME record pointing to the acme-dns account domain
+	SETUP_CNAME = "cname"
+	// SETUP_NS means _acme-challenge is delegated with NS records straight to the acme-dns server
+	SETUP_NS = "ns"
 )
 
+// accountInfo is the JSON representation of a stored acme-dns account. The account credentials are left out.
+type accountInfo struct {
+	Domain      string           `json:"domain"`
+	FullDomain  string           `json:"fulldomain"`
+	SubDomain   string           `json:"subdomain"`
+	ServerURL   string           `json:"server_url"`
+	Status      string           `json:"status,omitempty"`
+	Error       string           `json:"error,omitempty"`
+	CNAMEChain  []string         `json:"cname_chain,omitempty"`
+	Setup       string           `json:"setup,omitempty"`
+	NameServers []string         `json:"name_servers,omitempty"`
+	Stale       bool             `json:"stale"`
+	Metadata    storage.Metadata `json:"metadata"`
+}
+
+func (c *AcmednsClient) newAccountInfo(domain string, acct goacmedns.Account) accountInfo {
+	md, err := c.Storage.FetchMetadata(domain)
+	if err != nil {
+		c.Debug(fmt.Sprintf("Could not fetch account metadata for %s: %s", domain, err))
+	}
+	return accountInfo{
+		Domain:     domain,
+		FullDomain: acct.FullDomain,
+		SubDomain:  acct.SubDomain,
+		ServerURL:  acct.ServerURL,
+		Metadata:   md,
+	}
+}
+
+// accountStatus checks the CNAME record of the domain and sets the status of the account accordingly
+func (c *AcmednsClient) accountStatus(info *accountInfo) {
+	dnsc := c.dnsClient()
+	cname, err := dnsc.GetCNAME(info.Domain)
+	info.CNAMEChain = cname.Chain
+	if cname.HasCNAME {
+		info.Setup = SETUP_CNAME
+	} else if d, derr := dnsc.GetDelegation(info.Domain, info.FullDomain); derr == nil {
+		info.Setup = SETUP_NS
+		info.NameServers = d.NameServers
+		if d.Working() {
+			info.Status = ACCOUNT_WORKING
+		} else {
+			info.Status = ACCOUNT_DYSFUNCTIONAL
+			info.Error = delegationIssue(d)
+		}
+		return
+	}
+	if err == dnsclient.ErrCNAMERecordNotFound {
+		// Neither a CNAME record nor a delegation is set up for the challenge domain
+		info.Status = ACCOUNT_DYSFUNCTIONAL
+		info.Error = err.Error()
+	} else if err != nil {
+		info.Status = ACCOUNT_ERROR
+		info.Error = err.Error()
+	} else if cname.CorrectTarget(info.FullDomain) {
+		info.Status = ACCOUNT_WORKING
+	} else {
+		info.Status = ACCOUNT_DYSFUNCTIONAL
+	}
+}
+
+// delegationIssue returns the issue of the first delegated name server not working
+func delegationIssue(d dnsclient.Delegation) string {
+	for _, s := range d.Servers {
+		if !s.Working() {
+			return delegatedServerIssue(d.Name, s)
+		}
+	}
+	return "No delegated name servers could be checked"
+}
+
+// printJSON outputs the value as indented JSON data
+func printJSON(v interface{}) {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		PrintError(fmt.Sprintf("Could not encode JSON output: %s", err), 0)
+		return
+	}
+	PrintData("%s\n", out)
+}
+
 func (c *AcmednsClient) List() {
 	adnsAccts := c.Storage.FetchAll()
-	functional := make([]string, 0)
-	dysfunctional := make([]string, 0)
-	errored := make([]string, 0)
+	domains := make([]string, 0, len(adnsAccts))
+	for d := range adnsAccts {
+		domains = append(domains, d)
+	}
+	sort.Strings(domains)
 
-	if len(adnsAccts) == 0 {
-		fmt.Printf("No acme-dns accounts were found on this system.\n")
-	} else {
-		fmt.Printf("Number of acme-dns accounts found on this system: %d\nPerforming CNAME checks...\n\n", len(adnsAccts))
-		for d, acct := range adnsAccts {
-			dnsc := dnsclient.NewDNSClient(c.Config.DNSServer)
-			cname, err := dnsc.GetCNAME(d)
-			if err != nil {
-				errored = append(errored, fmt.Sprintf("%s (%s)", d, err))
-			} else if cname.CorrectTarget(acct.FullDomain) {
-				functional = append(functional, d)
-			} else {
-				dysfunctional = append(dysfunctional, d)
+	accounts := make([]accountInfo, len(domains))
+	staleWindow := time.Duration(c.Config.StaleDays) * 24 * time.Hour
+	now := time.Now()
+	for i, d := range domains {
+		accounts[i] = c.newAccountInfo(d, adnsAccts[d])
+		accounts[i].Stale = isStale(accounts[i].Metadata, staleWindow, now)
+	}
+	// The CNAME checks are done concurrently, the results keep the sorted order of the domains
+	c.forEach(len(accounts), func(i int) {
+		c.accountStatus(&accounts[i])
+	})
+	if c.Config.JSON {
+		printJSON(accounts)
+		return
+	}
+
+	if len(accounts) == 0 {
+		Printf("No acme-dns accounts were found on this system.\n")
+		return
+	}
+	Printf("Number of acme-dns accounts found on this system: %d\nPerforming CNAME and NS delegation checks...\n\n", len(accounts))
+	sections := []struct {
+		status string
+		title  string
+		print  func(string, int)
+	}{
+		{ACCOUNT_WORKING, "Working", PrintSuccess},
+		{ACCOUNT_ERROR, "Error", PrintError},
+		{ACCOUNT_DYSFUNCTIONAL, "Dysfunctional", PrintWarning},
+	}
+	for _, sec := range sections {
+		header := false
+		for _, a := range accounts {
+			if a.Status != sec.status {
+				continue
+			}
+			if !header {
+				Printf("%s:\n", sec.title)
+				header = true
+			}
+			line := a.Domain
+			if a.Setup == SETUP_NS {
+				line = fmt.Sprintf("%s (NS delegation to %s)", line, strings.Join(a.NameServers, ", "))
+			}
+			if a.Error != "" {
+				line = fmt.Sprintf("%s (%s)", line, a.Error)
+			}
+			sec.print(line, 0)
+			if summary := metadataSummary(a.Metadata); summary != "" {
+				Printf("    %s\n", summary)
 			}
 		}
-	}
-	if len(functional) > 0 {
-		fmt.Printf("Working:\n")
-		for _, s := range functional {
-			PrintSuccess(s, 0)
+		if header {
+			Printf("\n")
 		}
-		fmt.Println()
 	}
-	if len(errored) > 0 {
-		fmt.Printf("Error:\n")
-		for _, e := range errored {
-			PrintError(e, 0)
+	stale := make([]string, 0)
+	for _, a := range accounts {
+		if a.Stale {
+			stale = append(stale, a.Domain)
 		}
-		fmt.Println()
 	}
-	if len(dysfunctional) > 0 {
-		fmt.Printf("Dysfunctional:\n")
-		for _, d := range dysfunctional {
+	if len(stale) > 0 {
+		Printf("Stale, no successful validation in the last %d days:\n", c.Config.StaleDays)
+		for _, d := range stale {
 			PrintWarning(d, 0)
 		}
+		Printf("\n")
 	}
 }
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/acme-dns/acme-dns-client/pkg/logging"
+
+	"github.com/cpu/goacmedns"
+)
+
+// Error classes attached to the log messages of failed operations
+const (
+	ERROR_CLASS_ACCOUNT = "account"
+	ERROR_CLASS_NETWORK = "network"
+	ERROR_CLASS_SERVER  = "server"
+	ERROR_CLASS_OTHER   = "other"
+)
+
+// logger receives all the console output in addition to the structured events of the client
+var logger logging.Logger = logging.Discard
+
+// SetLogger sets the logging backend. The previous backend is closed.
+func SetLogger(l logging.Logger) {
+	_ = logger.Close()
+	logger = l
+}
+
+// logOutput forwards console output to the logging backend
+func logOutput(level logging.Level, input string) {
+	_ = logger.Log(level, strings.TrimSpace(input), nil)
+}
+
+// logEvent logs an operation on an acme-dns account with structured fields
+func (c *AcmednsClient) logEvent(level logging.Level, msg string, domain string, acct goacmedns.Account, opErr error) {
+	fields := logging.Fields{
+		logging.FIELD_DOMAIN: domain,
+	}
+	if acct.FullDomain != "" {
+		fields[logging.FIELD_ACCOUNT] = acct.FullDomain
+	}
+	if acct.ServerURL != "" {
+		fields[logging.FIELD_SERVER] = acct.ServerURL
+	}
+	if c.caller != "" {
+		fields[logging.FIELD_INTEGRATION] = c.caller
+	}
+	if opErr != nil {
+		fields[logging.FIELD_ERROR_CLASS] = errorClass(opErr)
+		msg = msg + ": " + opErr.Error()
+	}
+	_ = logger.Log(level, msg, fields)
+}
+
+// classifiedError is an error with an explicit error class, for errors that cannot be categorized by their type
+type classifiedError struct {
+	class string
+	err   error
+}
+
+func (e classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e classifiedError) Unwrap() error {
+	return e.err
+}
+
+// errorClass categorizes the error for the logs
+func errorClass(err error) string {
+	var classified classifiedError
+	var netErr net.Error
+	var clientErr goacmedns.ClientError
+	switch {
+	case errors.As(err, &classified):
+		return classified.class
+	case errors.Is(err, goacmedns.ErrDomainNotFound):
+		return ERROR_CLASS_ACCOUNT
+	case errors.As(err, &clientErr):
+		return ERROR_CLASS_SERVER
+	case errors.As(err, &netErr):
+		return ERROR_CLASS_NETWORK
+	}
+	return ERROR_CLASS_OTHER
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/acme-dns/acme-dns-client/pkg/storage"
+)
+
+// newAccountMetadata returns the metadata for an account being registered with the current configuration
+func (c *AcmednsClient) newAccountMetadata(allowFrom []string) storage.Metadata {
+	now := time.Now().UTC()
+	host, err := os.Hostname()
+	if err != nil {
+		c.Debug(fmt.Sprintf("Could not determine the hostname: %s", err))
+	}
+	return storage.Metadata{
+		Registered: &now,
+		Host:       host,
+		Server:     c.Config.Server,
+		AllowList:  allowFrom,
+		Notes:      c.Config.Notes,
+		Tags:       splitTags(c.Config.Tags),
+	}
+}
+
+// recordValidation stores the outcome of a TXT record update attempt for the domain. Failures to store it are
+// not fatal, as the outcome of the update itself is reported separately.
+func (c *AcmednsClient) recordValidation(domain string, updateErr error) {
+	now := time.Now().UTC()
+	err := c.Storage.UpdateMetadata(domain, func(md *storage.Metadata) {
+		md.LastAttempt = &now
+		if updateErr != nil {
+			md.LastError = updateErr.Error()
+			md.Failures++
+		} else {
+			md.LastValidated = &now
+			md.LastError = ""
+			md.Validations++
+		}
+	})
+	if err == nil {
+		err = c.Storage.Save()
+	}
+	if err != nil {
+		c.Verbose(fmt.Sprintf("Could not store the validation history for %s: %s", domain, err))
+	}
+}
+
+// isStale returns true if the account has not been successfully validated within the window. Accounts without
+// any recorded history, eg. ones migrated from the older storage format, are not considered stale.
+func isStale(md storage.Metadata, window time.Duration, now time.Time) bool {
+	if window <= 0 {
+		return false
+	}
+	last := md.LastValidated
+	if last == nil {
+		last = md.Registered
+	}
+	return last != nil && now.Sub(*last) > window
+}
+
+// daysAgo returns a human readable description of the time elapsed since t in days
+func daysAgo(t time.Time, now time.Time) string {
+	days := int(now.Sub(t).Hours() / 24)
+	switch days {
+	case 0:
+		return "today"
+	case 1:
+		return "1 day ago"
+	}
+	return fmt.Sprintf("%d days ago", days)
+}
+
+// splitTags splits a comma separated list of tags, dropping the empty ones
+func splitTags(tags string) []string {
+	out := make([]string, 0)
+	for _, t := range strings.Split(tags, ",") {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// metadataSummary returns a single line description of the account metadata for listings
+func metadataSummary(md storage.Metadata) string {
+	parts := make([]string, 0)
+	if md.Registered != nil {
+		reg := "registered " + md.Registered.Local().Format("2006-01-02")
+		if md.Host != "" {
+			reg += " on " + md.Host
+		}
+		parts = append(parts, reg)
+	}
+	if md.LastValidated != nil {
+		parts = append(parts, "last validated "+daysAgo(*md.LastValidated, time.Now()))
+	} else {
+		parts = append(parts, "no validation recorded")
+	}
+	if md.LastError != "" {
+		parts = append(parts, "last error: "+md.LastError)
+	}
+	if len(md.Tags) > 0 {
+		parts = append(parts, "tags: "+strings.Join(md.Tags, ", "))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatTime formats a timestamp for display, handling the unset value
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "unknown"
+	}
+	return t.Local().Format(time.RFC1123)
+}
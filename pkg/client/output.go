@@ -3,65 +3,212 @@ package client
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"runtime"
 	"strings"
 	"time"
 
 	"github.com/acme-dns/acme-dns-client/pkg/integration"
+	"github.com/acme-dns/acme-dns-client/pkg/logging"
 )
 
-func successMarker() string {
-	return fmt.Sprintf("[%s*%s]", ANSI_GREEN, ANSI_CLEAR)
+// OutputLevel is the level of a message written to the output
+type OutputLevel int
+
+const (
+	OutputDebug OutputLevel = iota
+	OutputInfo
+	OutputSuccess
+	OutputWarning
+	OutputError
+)
+
+// Color modes for the console output
+const (
+	COLOR_AUTO   = "auto"
+	COLOR_ALWAYS = "always"
+	COLOR_NEVER  = "never"
+)
+
+// Sink receives the user facing output of the client. Library users can provide their own implementation with
+// SetOutput, or for a single client with the AcmednsClient.Output field.
+type Sink interface {
+	// Message outputs a leveled message, indented with offset spaces
+	Message(level OutputLevel, msg string, offset int)
+	// Text outputs informational free form text, like setup instructions
+	Text(text string)
+	// Data outputs the data requested by the user, like DNS record snippets or audit log entries
+	Data(text string)
 }
 
-func infoMarker() string {
-	return fmt.Sprintf("[%si%s]", ANSI_BLUE, ANSI_CLEAR)
+// Console is a Sink writing to the standard streams. Errors, warnings and debug messages are written to stderr,
+// everything else to stdout. In quiet mode only warnings, errors, the requested data and debug output are written.
+type Console struct {
+	Stdout io.Writer
+	Stderr io.Writer
+	// Color enables ANSI colors for the stdout and stderr output respectively
+	ColorStdout bool
+	ColorStderr bool
+	Quiet       bool
 }
 
-func warningMarker() string {
-	return fmt.Sprintf("[%sW%s]", ANSI_YELLOW, ANSI_CLEAR)
+// NewConsole returns a Console writing to the standard streams. The color mode is one of auto, always or never,
+// in auto mode the colors are used for terminals unless disabled with NO_COLOR environment variable.
+func NewConsole(color string, quiet bool) (*Console, error) {
+	con := &Console{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Quiet:  quiet,
+	}
+	switch color {
+	case COLOR_AUTO, "":
+		con.ColorStdout = useColor(os.Stdout)
+		con.ColorStderr = useColor(os.Stderr)
+	case COLOR_ALWAYS:
+		con.ColorStdout = true
+		con.ColorStderr = true
+	case COLOR_NEVER:
+	default:
+		return con, fmt.Errorf("Unknown color mode %q, expected one of: %s, %s, %s", color, COLOR_AUTO, COLOR_ALWAYS, COLOR_NEVER)
+	}
+	return con, nil
 }
 
-func errorMarker() string {
-	return fmt.Sprintf("[%s!%s]", ANSI_RED, ANSI_CLEAR)
+// useColor returns true if ANSI colors should be used for the file in auto mode
+func useColor(f *os.File) bool {
+	if _, set := os.LookupEnv("NO_COLOR"); set || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	if !logging.IsTerminal(f) {
+		return false
+	}
+	// Legacy Windows consoles do not interpret ANSI escape sequences, Windows Terminal does
+	if runtime.GOOS == "windows" && os.Getenv("WT_SESSION") == "" {
+		return false
+	}
+	return true
 }
 
-func debugMarker() string {
-	return fmt.Sprintf("[%sD%s]", ANSI_YELLOW, ANSI_CLEAR)
+func (con *Console) Message(level OutputLevel, msg string, offset int) {
+	w, color := con.Stdout, con.ColorStdout
+	switch level {
+	case OutputInfo, OutputSuccess:
+		if con.Quiet {
+			return
+		}
+	default:
+		w, color = con.Stderr, con.ColorStderr
+	}
+	padding := strings.Repeat(" ", offset)
+	fmt.Fprintf(w, "%s%s %s\n", padding, marker(level, color), msg)
+}
+
+func (con *Console) Text(text string) {
+	if con.Quiet {
+		return
+	}
+	fmt.Fprint(con.Stdout, text)
+}
+
+func (con *Console) Data(text string) {
+	fmt.Fprint(con.Stdout, text)
+}
+
+// marker returns the message prefix for the level
+func marker(level OutputLevel, color bool) string {
+	var sym, ansi string
+	switch level {
+	case OutputDebug:
+		sym, ansi = "D", ANSI_YELLOW
+	case OutputInfo:
+		sym, ansi = "i", ANSI_BLUE
+	case OutputSuccess:
+		sym, ansi = "*", ANSI_GREEN
+	case OutputWarning:
+		sym, ansi = "W", ANSI_YELLOW
+	default:
+		sym, ansi = "!", ANSI_RED
+	}
+	if !color {
+		return fmt.Sprintf("[%s]", sym)
+	}
+	return fmt.Sprintf("[%s%s%s]", ansi, sym, ANSI_CLEAR)
+}
+
+// output is the Sink used by the package level output functions
+var output Sink = defaultConsole()
+
+func defaultConsole() *Console {
+	con, _ := NewConsole(COLOR_AUTO, false)
+	return con
+}
+
+// SetOutput sets the Sink used by the package level output functions and new clients
+func SetOutput(s Sink) {
+	output = s
+}
+
+// ConfigureOutput sets up the console output according to the color and quiet settings of the client
+func (c *AcmednsClient) ConfigureOutput() error {
+	con, err := NewConsole(c.Config.Color, c.Config.Quiet)
+	if err != nil {
+		return err
+	}
+	SetOutput(con)
+	c.Output = con
+	return nil
 }
 
 func PrintError(input string, offset int) {
-	padding := strings.Repeat(" ", offset)
-	fmt.Printf("%s%s %s\n", padding, errorMarker(), input)
+	logOutput(logging.LevelError, input)
+	output.Message(OutputError, input, offset)
 }
 
 func PrintInfo(input string, offset int) {
-	padding := strings.Repeat(" ", offset)
-	fmt.Printf("%s%s %s\n", padding, infoMarker(), input)
+	logOutput(logging.LevelInfo, input)
+	output.Message(OutputInfo, input, offset)
 }
 
 func PrintWarning(input string, offset int) {
-	padding := strings.Repeat(" ", offset)
-	fmt.Printf("%s%s %s\n", padding, warningMarker(), input)
+	logOutput(logging.LevelWarning, input)
+	output.Message(OutputWarning, input, offset)
 }
 
 func PrintSuccess(input string, offset int) {
-	padding := strings.Repeat(" ", offset)
-	fmt.Printf("%s%s %s\n", padding, successMarker(), input)
+	logOutput(logging.LevelInfo, input)
+	output.Message(OutputSuccess, input, offset)
 }
 
 func PrintDebug(input string, offset int) {
-	padding := strings.Repeat(" ", offset)
-	fmt.Printf("%s%s %s\n", padding, debugMarker(), input)
+	logOutput(logging.LevelDebug, input)
+	output.Message(OutputDebug, input, offset)
+}
+
+// Printf outputs informational text, which is left out in quiet mode
+func Printf(format string, a ...interface{}) {
+	output.Text(fmt.Sprintf(format, a...))
 }
 
+// PrintData outputs the data requested by the user, which is written also in quiet mode
+func PrintData(format string, a ...interface{}) {
+	output.Data(fmt.Sprintf(format, a...))
+}
+
+// YesNoPrompt asks the question on the controlling terminal, so that the prompts work even if the standard
+// streams are redirected. The default value is returned if there is no terminal.
 func YesNoPrompt(question string, defVal bool) bool {
-	reader := bufio.NewReader(os.Stdin)
+	tty, err := openTerminal()
+	if err != nil {
+		return defVal
+	}
+	defer tty.Close()
 	if defVal {
-		fmt.Printf("%s [Y/n]: ", question)
+		fmt.Fprintf(tty.out, "%s [Y/n]: ", question)
 	} else {
-		fmt.Printf("%s [y/N]: ", question)
+		fmt.Fprintf(tty.out, "%s [y/N]: ", question)
 	}
+	reader := bufio.NewReader(tty.in)
 	inp, _ := reader.ReadString('\n')
 	inp = strings.TrimSpace(inp)
 	if strings.ToLower(inp) == "y" {
@@ -74,21 +221,28 @@ func YesNoPrompt(question string, defVal bool) bool {
 
 func (c *ConfigurationState) PrintACMEAccountInfo(accs []integration.ACMEAccount) {
 	if len(accs) > 0 {
-		fmt.Printf("\n - ACME accounts found on the system:\n")
+		Printf("\n - ACME accounts found on the system:\n")
 		for _, v := range accs {
 			PrintInfo(fmt.Sprintf("URI: \t%s", v.URI), 2)
 			PrintInfo(fmt.Sprintf("Contact: \t%s", v.Contact), 2)
 			PrintInfo(fmt.Sprintf("File: \t%s", v.FilePath), 2)
 			PrintInfo(fmt.Sprintf("Client: \t%s", v.Client), 2)
-			fmt.Printf(" --------\n")
+			Printf(" --------\n")
 		}
 	}
 }
 
+// printPauseCounter waits for the number of seconds, showing a countdown on the controlling terminal
 func printPauseCounter(seconds int) {
+	tty, err := openTerminal()
+	if err != nil {
+		time.Sleep(time.Duration(seconds) * time.Second)
+		return
+	}
+	defer tty.Close()
 	for i := 0; i < seconds; i++ {
-		fmt.Fprintf(os.Stderr, "%sWaiting for %d seconds... Press Ctrl + C to abort and exit.", TERMINAL_CLEAR_LINE, seconds - i)
+		fmt.Fprintf(tty.out, "%sWaiting for %d seconds... Press Ctrl + C to abort and exit.", TERMINAL_CLEAR_LINE, seconds-i)
 		time.Sleep(1 * time.Second)
 	}
-	fmt.Fprintf(os.Stderr, "%s", TERMINAL_CLEAR_LINE)
-}
\ No newline at end of file
+	fmt.Fprintf(tty.out, "%s", TERMINAL_CLEAR_LINE)
+}
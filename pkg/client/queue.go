@@ -0,0 +1,126 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/acme-dns/acme-dns-client/pkg/flock"
+)
+
+// pendingChallenge is a TXT record value published for a challenge that has not been cleaned up yet
+type pendingChallenge struct {
+	Domain string    `json:"domain"`
+	Token  string    `json:"token"`
+	Added  time.Time `json:"added"`
+}
+
+// challengeQueue tracks the pending challenges of a single acme-dns account across acme-dns-client processes,
+// so that a TXT record update would not evict a token the CA has not checked yet.
+type challengeQueue struct {
+	path    string
+	timeout time.Duration
+}
+
+// queueFor returns the challenge queue for the acme-dns account
+func (c *AcmednsClient) queueFor(fulldomain string) (*challengeQueue, error) {
+	dir := filepath.Join(c.StateDir, "queue")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &challengeQueue{
+		path:    filepath.Join(dir, fulldomain+".json"),
+		timeout: c.Config.QueueTimeout,
+	}, nil
+}
+
+// withLock runs the function with the queue file locked, and saves the pending challenges it returns
+func (q *challengeQueue) withLock(fn func([]pendingChallenge) []pendingChallenge) error {
+	lock, err := flock.Acquire(q.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	pending := make([]pendingChallenge, 0)
+	if data, err := ioutil.ReadFile(q.path); err == nil {
+		if err = json.Unmarshal(data, &pending); err != nil {
+			return fmt.Errorf("Could not parse challenge queue %s: %s", q.path, err)
+		}
+	}
+	data, err := json.Marshal(fn(pending))
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(q.path, data, 0600)
+}
+
+// active returns the pending challenges that have not timed out
+func (q *challengeQueue) active(pending []pendingChallenge) ([]pendingChallenge, []pendingChallenge) {
+	active := make([]pendingChallenge, 0)
+	expired := make([]pendingChallenge, 0)
+	for _, p := range pending {
+		if time.Since(p.Added) > q.timeout {
+			expired = append(expired, p)
+		} else {
+			active = append(active, p)
+		}
+	}
+	return active, expired
+}
+
+// reserve waits until the account has a free TXT record slot, and reserves it for the token. Returns
+// true if the wait timed out and the update will evict a token of a pending challenge.
+func (q *challengeQueue) reserve(domain string, token string, warn func(string)) (bool, error) {
+	deadline := time.Now().Add(q.timeout)
+	warned := false
+	for {
+		reserved := false
+		var pendingDomains []string
+		err := q.withLock(func(pending []pendingChallenge) []pendingChallenge {
+			active, expired := q.active(pending)
+			for _, e := range expired {
+				warn(fmt.Sprintf("Challenge for %s timed out without a cleanup, releasing its TXT record slot", e.Domain))
+			}
+			for _, a := range active {
+				if a.Token == token {
+					reserved = true
+					return active
+				}
+			}
+			if len(active) < maxTokensPerAccount || time.Now().After(deadline) {
+				reserved = true
+				return append(active, pendingChallenge{Domain: domain, Token: token, Added: time.Now()})
+			}
+			pendingDomains = make([]string, 0)
+			for _, a := range active {
+				pendingDomains = append(pendingDomains, a.Domain)
+			}
+			return active
+		})
+		if err != nil || reserved {
+			return reserved && time.Now().After(deadline), err
+		}
+		if !warned {
+			warn(fmt.Sprintf("acme-dns account already has %d pending challenges (%v), updating it now would evict a token the CA has not checked yet. Waiting for a cleanup.",
+				maxTokensPerAccount, pendingDomains))
+			warned = true
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// release removes the token from the pending challenges
+func (q *challengeQueue) release(token string) error {
+	return q.withLock(func(pending []pendingChallenge) []pendingChallenge {
+		remaining := make([]pendingChallenge, 0)
+		for _, p := range pending {
+			if p.Token != token {
+				remaining = append(remaining, p)
+			}
+		}
+		return remaining
+	})
+}
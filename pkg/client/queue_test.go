@@ -0,0 +1,94 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client with the storage and state in a temporary directory removed after the test
+func newTestClient(t *testing.T) *AcmednsClient {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "acmedns-client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return NewAcmednsClient(filepath.Join(dir, "clientstorage.json"))
+}
+
+func TestChallengeQueue(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// pending are the tokens reserved and released before the tested reservation, "-" releases the token
+		pending []string
+		token   string
+		evicts  bool
+		warning string
+	}{
+		{"free slot", []string{"one"}, "two", false, ""},
+		{"already reserved", []string{"one", "two"}, "two", false, ""},
+		{"released slot", []string{"one", "two", "-one"}, "three", false, ""},
+		{"timed out", []string{"one", "two"}, "three", true, "pending challenges"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestClient(t)
+			c.Config.QueueTimeout = 100 * time.Millisecond
+			queue, err := c.queueFor("abc.auth.example.net")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range tc.pending {
+				if strings.HasPrefix(p, "-") {
+					err = queue.release(p[1:])
+				} else {
+					_, err = queue.reserve("example.org", p, func(string) {})
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			warnings := make([]string, 0)
+			evicts, err := queue.reserve("example.org", tc.token, func(msg string) { warnings = append(warnings, msg) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if evicts != tc.evicts {
+				t.Errorf("Expected evicts to be %t, got %t", tc.evicts, evicts)
+			}
+			if tc.warning == "" && len(warnings) > 0 {
+				t.Errorf("Expected no warnings, got %v", warnings)
+			}
+			if tc.warning != "" && (len(warnings) == 0 || !strings.Contains(warnings[0], tc.warning)) {
+				t.Errorf("Expected a warning containing %q, got %v", tc.warning, warnings)
+			}
+		})
+	}
+}
+
+func TestChallengeQueueExpiry(t *testing.T) {
+	c := newTestClient(t)
+	c.Config.QueueTimeout = 100 * time.Millisecond
+	queue, err := c.queueFor("abc.auth.example.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{"one", "two"} {
+		if _, err = queue.reserve("example.org", token, func(string) {}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(150 * time.Millisecond)
+	// A new reservation releases the challenges that timed out without a cleanup, without waiting
+	warnings := make([]string, 0)
+	evicts, err := queue.reserve("example.com", "three", func(msg string) { warnings = append(warnings, msg) })
+	if err != nil || evicts {
+		t.Errorf("Expected a free slot after the pending challenges timed out, got %t (%v)", evicts, err)
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "timed out without a cleanup") {
+		t.Errorf("Expected a warning for both timed out challenges, got %v", warnings)
+	}
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/acme-dns/acme-dns-client/pkg/integration"
+	"github.com/acme-dns/acme-dns-client/pkg/records"
+)
+
+// Records prints out the CNAME record required by the acme-dns account of the domain, and the recommended
+// CAA records for the ACME accounts found on the system in the configured record format.
+func (c *AcmednsClient) Records() {
+	if c.Config.Domain == "" {
+		PrintError("No domain name provided, please use -d to define one", 0)
+		return
+	}
+	acct, err := c.acmeDnsAccountForDomain(c.Config.Domain)
+	if err != nil {
+		PrintError(fmt.Sprintf("Error while trying to fetch acme-dns account from storage: %s", err), 0)
+		return
+	}
+	if acct.FullDomain == "" {
+		PrintError(fmt.Sprintf("No acme-dns account registered for domain %s", c.Config.Domain), 0)
+		return
+	}
+	recs := []records.Record{records.ChallengeCNAME(c.Config.Domain, acct.FullDomain)}
+	recs = append(recs, c.caaRecords(c.Config.Domain, c.findACMEAccounts())...)
+	out, err := records.Format(c.Config.RecordFormat, c.Config.Domain, recs)
+	if err != nil {
+		PrintError(fmt.Sprintf("%s", err), 0)
+		return
+	}
+	PrintData("%s", out)
+}
+
+// caaRecords returns the recommended CAA records for the domain, limiting the issuance to the ACME accounts
+func (c *AcmednsClient) caaRecords(domain string, accts []integration.ACMEAccount) []records.Record {
+	recs := make([]records.Record, 0)
+	for _, a := range accts {
+		value, err := a.CAAValue()
+		if err != nil {
+			c.Verbose(fmt.Sprintf("Error while generating CAA record value: %s", err))
+			continue
+		}
+		recs = append(recs, records.CAAPair(domain, value)...)
+	}
+	return recs
+}
+
+// formatRecords returns the records in the record format configured by the user
+func (c *AcmednsClient) formatRecords(domain string, recs []records.Record) string {
+	out, err := records.Format(c.Config.RecordFormat, domain, recs)
+	if err != nil {
+		PrintError(fmt.Sprintf("%s", err), 0)
+		return ""
+	}
+	return out
+}
@@ -5,6 +5,9 @@ import (
 	"os"
 	"strings"
 
+	"github.com/acme-dns/acme-dns-client/pkg/logging"
+	"github.com/acme-dns/acme-dns-client/pkg/records"
+
 	"github.com/cpu/goacmedns"
 )
 
@@ -17,8 +20,7 @@ to the newly created acme-dns domain %s
 
 A correctly set up CNAME record should look like the following:
 
-_acme-challenge.%s.     IN      CNAME   %s.
-
+%s
 `
 	CHECK_INFO = `
 After setting up the CNAME record to your main DNS zone, you can use acme-dns-client to check the configuration.
@@ -90,7 +92,10 @@ func (c *AcmednsClient) Register() {
 		}
 		c.Debug("Registering new account with the acme-dns server")
 		newAccount, err := client.RegisterAccount(allowFrom)
+		c.auditRegister(c.Config.Domain, newAccount, err)
 		if err != nil {
+			c.logEvent(logging.LevelError, "Account registration failed", c.Config.Domain,
+				goacmedns.Account{ServerURL: c.Config.Server}, err)
 			PrintError(fmt.Sprintf("%s", err), 0)
 			return
 		}
@@ -102,6 +107,11 @@ func (c *AcmednsClient) Register() {
 			PrintError(fmt.Sprintf("%s", err), 0)
 			return
 		}
+		err = c.Storage.PutMetadata(c.Config.Domain, c.newAccountMetadata(allowFrom))
+		if err != nil {
+			PrintError(fmt.Sprintf("%s", err), 0)
+			return
+		}
 
 		c.Debug("Saving the acme-dns account storage to disk")
 		err = c.Storage.Save()
@@ -109,11 +119,20 @@ func (c *AcmednsClient) Register() {
 			PrintError(fmt.Sprintf("%s", err), 0)
 			return
 		}
+		c.logEvent(logging.LevelInfo, "Account registered", c.Config.Domain, cstate.Account, nil)
 		PrintSuccess(fmt.Sprintf("New acme-dns account for domain %s successfully registered!\n", c.Config.Domain), 0)
 	}
 
 	if cstate.CorrectCNAME() {
 		PrintSuccess("CNAME record seems to already be set up correctly, you are good to go", 0)
+	} else if cstate.CorrectDelegation() {
+		PrintSuccess("NS delegation of _acme-challenge seems to already be set up correctly, you are good to go", 0)
+	} else if c.canPublishRecords() {
+		// Create the CNAME record using the DNS provider or dynamic update
+		if !c.publishCNAME(c.Config.Domain, cstate.Account.FullDomain) {
+			c.PrintRegistrationInfo(c.Config.Domain, cstate.Account)
+			Printf(CHECK_INFO, c.Config.Domain)
+		}
 	} else {
 		// Ask if user wants acme-dns-client to monitor CNAME change
 		if YesNoPrompt("Do you want acme-dns-client to monitor the CNAME record change?", true) {
@@ -121,22 +140,24 @@ func (c *AcmednsClient) Register() {
 		} else {
 			// if not, print post-check instruction
 			c.PrintRegistrationInfo(c.Config.Domain, cstate.Account)
-			fmt.Printf(CHECK_INFO, c.Config.Domain)
+			Printf(CHECK_INFO, c.Config.Domain)
 		}
 	}
 
-	if cstate.HasCAA() {
+	if c.Config.UpdateCAA && c.canPublishRecords() {
+		c.publishCAA(c.Config.Domain)
+	} else if cstate.HasCAA() {
 		c.Verbose("CAA record for the domain exists")
 		if cstate.HasAccountURI() {
 			c.Verbose("CAA accounturi for the domain exists")
 		} else {
-			fmt.Printf(CAA_INFO)
+			Printf(CAA_INFO)
 			if YesNoPrompt("Do you wish to set up a CAA record with accounturi now?", false) {
 				c.CAASetupWizard(c.Config.Domain)
 			}
 		}
 	} else {
-		fmt.Printf(CAA_INFO)
+		Printf(CAA_INFO)
 		if YesNoPrompt("Do you wish to set up a CAA record now?", false) {
 			c.CAASetupWizard(c.Config.Domain)
 		}
@@ -144,8 +165,9 @@ func (c *AcmednsClient) Register() {
 }
 
 func (c *AcmednsClient) PrintRegistrationInfo(domain string, account goacmedns.Account) {
-	fmt.Printf("Domain:         %s\n", account.FullDomain)
+	Printf("Domain:         %s\n", account.FullDomain)
 	c.Verbose(fmt.Sprintf("Username:   %s", account.Username))
-	c.Verbose(fmt.Sprintf("Password:   %s", account.Password))
-	fmt.Printf(CNAME_INFO, domain, account.FullDomain, domain, account.FullDomain)
+	c.verboseConsoleOnly(fmt.Sprintf("Password:   %s", account.Password))
+	Printf(CNAME_INFO, domain, account.FullDomain,
+		c.formatRecords(domain, []records.Record{records.ChallengeCNAME(domain, account.FullDomain)}))
 }
\ No newline at end of file
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cpu/goacmedns"
+)
+
+// Show prints the stored acme-dns account and its metadata for the configured domain
+func (c *AcmednsClient) Show() bool {
+	acct, err := c.Storage.Fetch(c.Config.Domain)
+	if err == goacmedns.ErrDomainNotFound {
+		PrintError(fmt.Sprintf("Domain %s does not have acme-dns account registered for it.", c.Config.Domain), 0)
+		return false
+	} else if err != nil {
+		PrintError(fmt.Sprintf("%s", err), 0)
+		return false
+	}
+	info := c.newAccountInfo(c.Config.Domain, acct)
+	if c.Config.JSON {
+		printJSON(info)
+		return true
+	}
+	md := info.Metadata
+	PrintData("Domain:          %s\n", info.Domain)
+	PrintData("Fulldomain:      %s\n", info.FullDomain)
+	PrintData("Subdomain:       %s\n", info.SubDomain)
+	PrintData("Server:          %s\n", info.ServerURL)
+	if c.Config.Verbose || c.Config.Debug {
+		PrintData("Username:        %s\n", acct.Username)
+		PrintData("Password:        %s\n", acct.Password)
+	}
+	PrintData("Registered:      %s\n", formatTime(md.Registered))
+	if md.Host != "" {
+		PrintData("Registered on:   %s\n", md.Host)
+	}
+	if len(md.AllowList) > 0 {
+		PrintData("Allowed from:    %s\n", strings.Join(md.AllowList, ", "))
+	}
+	PrintData("Last validated:  %s\n", formatTime(md.LastValidated))
+	PrintData("Last attempt:    %s\n", formatTime(md.LastAttempt))
+	if md.LastError != "" {
+		PrintData("Last error:      %s\n", md.LastError)
+	}
+	PrintData("Validations:     %d successful, %d failed\n", md.Validations, md.Failures)
+	if len(md.Tags) > 0 {
+		PrintData("Tags:            %s\n", strings.Join(md.Tags, ", "))
+	}
+	if md.Notes != "" {
+		PrintData("Notes:           %s\n", md.Notes)
+	}
+	return true
+}
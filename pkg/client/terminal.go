@@ -0,0 +1,18 @@
+package client
+
+import (
+	"os"
+)
+
+// terminal is the controlling terminal of the process, used for the interactive prompts
+type terminal struct {
+	in  *os.File
+	out *os.File
+}
+
+func (t *terminal) Close() {
+	t.in.Close()
+	if t.out != t.in {
+		t.out.Close()
+	}
+}
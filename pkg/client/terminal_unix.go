@@ -0,0 +1,18 @@
+// +build !windows
+
+package client
+
+import (
+	"os"
+)
+
+// TERMINAL_CLEAR_LINE moves the cursor to the beginning of the line and clears it
+const TERMINAL_CLEAR_LINE = "\r\x1b[2K"
+
+func openTerminal() (*terminal, error) {
+	f, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &terminal{in: f, out: f}, nil
+}
@@ -0,0 +1,22 @@
+package client
+
+import (
+	"os"
+)
+
+// TERMINAL_CLEAR_LINE moves the cursor to the beginning of the line. Legacy Windows consoles do not support
+// the ANSI sequence for clearing the line.
+const TERMINAL_CLEAR_LINE = "\r\r"
+
+func openTerminal() (*terminal, error) {
+	in, err := os.OpenFile("CONIN$", os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	out, err := os.OpenFile("CONOUT$", os.O_RDWR, 0)
+	if err != nil {
+		in.Close()
+		return nil, err
+	}
+	return &terminal{in: in, out: out}, nil
+}
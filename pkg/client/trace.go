@@ -0,0 +1,48 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/acme-dns/acme-dns-client/pkg/dnsclient"
+
+	"github.com/miekg/dns"
+)
+
+// PrintTrace prints the hops of the iterative resolution of the challenge TXT record, highlighting lame
+// delegations, glue mismatches and failed queries
+func (c *ConfigurationState) PrintTrace() {
+	Printf(" Tracing _acme-challenge.%s TXT from the root name servers:\n", c.Domain)
+	for _, hop := range c.Trace.Hops {
+		line := fmt.Sprintf("%s %s @ %s (%s) %s: %s", hop.Name, dns.TypeToString[hop.Type], hop.Server, hop.Address,
+			hop.Latency.Round(time.Millisecond), describeHop(hop))
+		switch hop.Kind {
+		case dnsclient.HopError:
+			PrintError(line, 2)
+		case dnsclient.HopLame:
+			PrintWarning(line, 2)
+		case dnsclient.HopAnswer:
+			PrintSuccess(line, 2)
+		default:
+			PrintInfo(line, 2)
+		}
+		for _, issue := range hop.Issues {
+			PrintWarning(issue, 4)
+		}
+	}
+	if c.TraceErr != nil {
+		PrintError(fmt.Sprintf("Trace failed: %s", c.TraceErr), 2)
+	}
+}
+
+// describeHop returns a description of the outcome of the query
+func describeHop(hop dnsclient.TraceHop) string {
+	switch hop.Kind {
+	case dnsclient.HopReferral, dnsclient.HopAnswer, dnsclient.HopCNAME:
+		return fmt.Sprintf("%s %s (TTL %d)", hop.Kind, strings.Join(hop.Records, ", "), hop.TTL)
+	case dnsclient.HopError:
+		return fmt.Sprintf("%s", hop.Err)
+	}
+	return hop.Kind.String()
+}
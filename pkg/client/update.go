@@ -0,0 +1,105 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/acme-dns/acme-dns-client/pkg/dnsclient"
+	"github.com/acme-dns/acme-dns-client/pkg/dnsprovider"
+	"github.com/acme-dns/acme-dns-client/pkg/records"
+)
+
+// canPublishRecords returns true if the user has configured a way for acme-dns-client to create DNS records
+func (c *AcmednsClient) canPublishRecords() bool {
+	return c.Config.Provider != "" || c.Config.TSIGKeyFile != ""
+}
+
+// publishRecords creates the records using the configured DNS provider API, or a RFC 2136 dynamic update if
+// a TSIG key was provided instead. When replace is true, existing records with the same name and type are replaced.
+func (c *AcmednsClient) publishRecords(domain string, recs []records.Record, replace bool) error {
+	if len(recs) == 0 {
+		return nil
+	}
+	if c.Config.Provider == "" {
+		return c.dynamicUpdate(recs, replace)
+	}
+	creds, err := dnsprovider.LoadCredentials(c.Config.ProviderConfig)
+	if err != nil {
+		return err
+	}
+	provider, err := dnsprovider.New(c.Config.Provider, creds)
+	if err != nil {
+		return err
+	}
+	if c.Config.DryRun {
+		PrintInfo(fmt.Sprintf("Dry run, not sending the following records to %s:", provider.Name()), 0)
+		Printf("%s\n", c.formatRecords(domain, recs))
+		return nil
+	}
+	c.Debug(fmt.Sprintf("Publishing %d record(s) using %s", len(recs), provider.Name()))
+	if replace {
+		return provider.CreateOrReplace(recs)
+	}
+	return dnsprovider.Add(provider, recs)
+}
+
+// dynamicUpdate publishes the records to the DNS zone using a TSIG authenticated RFC 2136 dynamic update.
+func (c *AcmednsClient) dynamicUpdate(recs []records.Record, replace bool) error {
+	key, err := dnsclient.ParseTSIGKeyFile(c.Config.TSIGKeyFile)
+	if err != nil {
+		return fmt.Errorf("Could not read TSIG key: %s", err)
+	}
+	c.Debug(fmt.Sprintf("Using TSIG key %s (%s)", key.Name, key.Algorithm))
+	dnsc := c.dnsClient()
+	zone, err := dnsc.FindZone(recs[0].Name)
+	if err != nil {
+		return err
+	}
+	server := zone.Primary
+	if c.Config.UpdateServer != "" {
+		server = c.Config.UpdateServer
+	}
+	c.Verbose(fmt.Sprintf("Found zone %s with primary name server %s", zone.Name, zone.Primary))
+	msg, err := dnsclient.NewUpdateMessage(zone.Name, recs, replace)
+	if err != nil {
+		return err
+	}
+	if c.Config.DryRun {
+		PrintInfo(fmt.Sprintf("Dry run, not sending the following dynamic update to %s:", server), 0)
+		Printf("%s\n", msg.String())
+		return nil
+	}
+	c.Debug(fmt.Sprintf("Sending dynamic update to %s", server))
+	return dnsclient.SendUpdate(msg, server, key)
+}
+
+// publishCNAME creates the CNAME record for the domain and waits for it to become visible
+func (c *AcmednsClient) publishCNAME(domain string, fulldomain string) bool {
+	err := c.publishRecords(domain, []records.Record{records.ChallengeCNAME(domain, fulldomain)}, true)
+	if err != nil {
+		PrintError(fmt.Sprintf("Could not create the CNAME record: %s", err), 0)
+		return false
+	}
+	if c.Config.DryRun {
+		return false
+	}
+	PrintSuccess("CNAME record was sent to the name server", 0)
+	return c.monitorCNAMERecordChange(domain, fulldomain)
+}
+
+// publishCAA adds the CAA records for the ACME accounts found on the system
+func (c *AcmednsClient) publishCAA(domain string) bool {
+	recs := c.caaRecords(domain, c.findACMEAccounts())
+	if len(recs) == 0 {
+		PrintWarning("Could not find ACME accounts on the system, not creating CAA records", 0)
+		return false
+	}
+	err := c.publishRecords(domain, recs, false)
+	if err != nil {
+		PrintError(fmt.Sprintf("Could not create the CAA records: %s", err), 0)
+		return false
+	}
+	if !c.Config.DryRun {
+		PrintSuccess("CAA records were sent to the name server", 0)
+	}
+	return true
+}
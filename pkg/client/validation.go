@@ -2,59 +2,213 @@ package client
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/acme-dns/acme-dns-client/pkg/integration"
+	"github.com/acme-dns/acme-dns-client/pkg/logging"
 
 	"github.com/cpu/goacmedns"
 )
 
 func (c *AcmednsClient) Validation() bool {
-	token := c.FindValidationToken()
-	c.Debug(fmt.Sprintf("Got validation token: %s", token))
-	domain := c.FindValidationDomain()
-	c.Debug(fmt.Sprintf("Got validation domain: %s", domain))
-	if domain == "" || token == "" {
+	intgr, err := c.DetectIntegration()
+	if err != nil {
+		PrintError(fmt.Sprintf("%s", err), 0)
 		return false
 	}
-	acct, err := c.Storage.Fetch(domain)
-	if err != nil && err != goacmedns.ErrDomainNotFound {
-		PrintError(fmt.Sprintf("Validation failed: %s", err), 0)
+	if intgr == nil {
 		return false
-	} else if err == goacmedns.ErrDomainNotFound {
-		PrintError(fmt.Sprintf("Domain %s does not have acme-dns account registered for it. Validation failed.", domain),0)
+	}
+	c.Debug(fmt.Sprintf("Invoked as a hook by %s", intgr.Name()))
+	c.caller = intgr.Name()
+	if intgr.HookEvent() == integration.HookIgnored {
+		c.Debug(fmt.Sprintf("Nothing to do for %s hook event", intgr.Name()))
+		return true
+	}
+	reqs, err := c.FindValidations(intgr)
+	if intgr.HookEvent() == integration.HookCleanup {
+		// acme-dns does not support removing TXT records, they get rotated out by the following updates.
+		// The TXT record slots reserved for the challenges are released though.
+		for _, r := range reqs {
+			c.releaseChallenge(r.Domain, r.Token)
+		}
+		return true
+	}
+	if err != nil {
+		PrintError(fmt.Sprintf("Could not read the validation request from %s: %s", intgr.Name(), err), 0)
 		return false
 	}
-	client := goacmedns.NewClient(acct.ServerURL)
-	err = client.UpdateTXTRecord(acct, token)
+	success := true
+	for _, r := range reqs {
+		if !c.queuedUpdateTXTRecord(r.Domain, r.Token) {
+			success = false
+		}
+	}
+	return success
+}
+
+// queuedUpdateTXTRecord waits for a free TXT record slot for the acme-dns account before updating it, so
+// that the tokens of challenges still pending validation do not get evicted
+func (c *AcmednsClient) queuedUpdateTXTRecord(domain string, token string) bool {
+	acct, err := c.accountForValidation(domain)
 	if err != nil {
-		PrintError(fmt.Sprintf("Validation failed: %s", err), 0)
+		PrintError(fmt.Sprintf("%s", err), 0)
+		return false
+	}
+	c.reserveChallenge(acct, domain, token)
+	if !c.updateTXTRecord(domain, token) {
+		c.releaseChallenge(domain, token)
 		return false
 	}
 	return true
 }
 
-func (c *AcmednsClient) FindValidationToken() string {
-	intgrs := integration.GetIntegrations()
-	for _, i := range intgrs {
-		token, err := i.FindValidationToken()
-		if err != nil {
-			c.Debug(fmt.Sprintf("%s", err))
-		} else {
-			return token
+// reserveChallenge waits for a free TXT record slot for the acme-dns account, and reserves it for the token.
+// The update goes ahead without a reservation if the challenge queue can not be used.
+func (c *AcmednsClient) reserveChallenge(acct goacmedns.Account, domain string, token string) {
+	queue, err := c.queueFor(acct.FullDomain)
+	if err == nil {
+		var evicts bool
+		evicts, err = queue.reserve(domain, token, func(msg string) { PrintWarning(msg, 0) })
+		if evicts {
+			PrintWarning(fmt.Sprintf("Timed out waiting for a free TXT record slot for %s, the update evicts a pending token", acct.FullDomain), 0)
 		}
 	}
-	return ""
+	if err != nil {
+		PrintWarning(fmt.Sprintf("Could not use the challenge queue, updating without it: %s", err), 0)
+	}
+}
+
+// releaseChallenge releases the TXT record slot reserved for the challenge
+func (c *AcmednsClient) releaseChallenge(domain string, token string) {
+	acct, err := c.accountForValidation(domain)
+	if err != nil {
+		c.Debug(fmt.Sprintf("%s", err))
+		return
+	}
+	queue, err := c.queueFor(acct.FullDomain)
+	if err == nil {
+		err = queue.release(token)
+	}
+	if err != nil {
+		c.Debug(fmt.Sprintf("Could not release the challenge for %s from the queue: %s", domain, err))
+	}
+}
+
+// Update updates the TXT record for the domain and token given on the command line
+func (c *AcmednsClient) Update() bool {
+	if c.Config.Domain == "" || c.Config.Token == "" {
+		PrintError("Both domain (-d) and token (-t) are required", 0)
+		return false
+	}
+	c.caller = "update"
+	return c.updateTXTRecord(c.Config.Domain, c.Config.Token)
 }
 
-func (c *AcmednsClient) FindValidationDomain() string {
-	intgrs := integration.GetIntegrations()
-	for _, i := range intgrs {
-		domain, err := i.FindValidationDomain()
-		if err != nil {
-			c.Debug(fmt.Sprintf("%s", err))
-		} else {
-			return domain
+// DetectIntegration returns the ACME client integration that invoked acme-dns-client as a hook, or nil if
+// none did. If the hook data of more than one ACME client is present, an error is returned instead of guessing.
+func (c *AcmednsClient) DetectIntegration() (integration.ACMEClient, error) {
+	matches := make([]integration.ACMEClient, 0)
+	names := make([]string, 0)
+	for _, i := range integration.GetIntegrations() {
+		if i.HookEvent() != integration.HookNone {
+			matches = append(matches, i)
+			names = append(names, i.Name())
 		}
 	}
-	return ""
-}
\ No newline at end of file
+	if len(matches) > 1 {
+		return nil, fmt.Errorf("Hook data for multiple ACME clients found (%s), refusing to guess. "+
+			"Use \"acme-dns-client update\" to update the TXT record explicitly.", strings.Join(names, ", "))
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	return matches[0], nil
+}
+
+// FindValidations returns the TXT record updates requested by the ACME client integration
+func (c *AcmednsClient) FindValidations(intgr integration.ACMEClient) ([]integration.ValidationRequest, error) {
+	if batch, ok := intgr.(integration.BatchValidator); ok {
+		reqs, err := batch.FindValidations()
+		c.Debug(fmt.Sprintf("Got %d validation request(s) from %s", len(reqs), intgr.Name()))
+		for i := range reqs {
+			reqs[i].Domain = c.registeredDomain(reqs[i].Domain)
+		}
+		return reqs, err
+	}
+	token, err := intgr.FindValidationToken()
+	if err != nil {
+		return nil, err
+	}
+	c.Debug(fmt.Sprintf("Got validation token: %s", token))
+	domain, err := intgr.FindValidationDomain()
+	if err != nil {
+		return nil, err
+	}
+	c.Debug(fmt.Sprintf("Got validation domain: %s", domain))
+	return []integration.ValidationRequest{{Domain: c.registeredDomain(domain), Token: token}}, nil
+}
+
+// registeredDomain returns the domain the acme-dns account was registered for. ACME clients following the
+// _acme-challenge CNAME record, like lego by default, pass the acme-dns account domain instead of the domain.
+func (c *AcmednsClient) registeredDomain(name string) string {
+	if _, err := c.Storage.Fetch(name); err == nil {
+		return name
+	}
+	accounts := c.Storage.FetchAll()
+	domains := make([]string, 0, len(accounts))
+	for d := range accounts {
+		domains = append(domains, d)
+	}
+	sort.Strings(domains)
+	for _, d := range domains {
+		if strings.EqualFold(strings.TrimSuffix(accounts[d].FullDomain, "."), name) {
+			c.Debug(fmt.Sprintf("%s is the acme-dns account domain of %s", name, d))
+			return d
+		}
+	}
+	return name
+}
+
+// updateTXTRecord updates the TXT record of the acme-dns account registered for the domain
+func (c *AcmednsClient) updateTXTRecord(domain string, token string) bool {
+	_, err := c.updateTXT(domain, token)
+	if err != nil {
+		PrintError(fmt.Sprintf("%s", err), 0)
+		return false
+	}
+	return true
+}
+
+// updateTXT updates the TXT record of the acme-dns account registered for the domain, and returns the account
+func (c *AcmednsClient) updateTXT(domain string, token string) (goacmedns.Account, error) {
+	acct, err := c.accountForValidation(domain)
+	if err != nil {
+		c.auditTXTUpdate(domain, acct, token, err)
+		c.logEvent(logging.LevelError, "TXT record update failed", domain, acct, err)
+		return acct, err
+	}
+	client := goacmedns.NewClient(acct.ServerURL)
+	err = client.UpdateTXTRecord(acct, token)
+	c.recordValidation(domain, err)
+	c.auditTXTUpdate(domain, acct, token, err)
+	if err != nil {
+		c.logEvent(logging.LevelError, "TXT record update failed", domain, acct, err)
+		return acct, fmt.Errorf("Validation failed: %s", err)
+	}
+	c.logEvent(logging.LevelInfo, "TXT record updated", domain, acct, nil)
+	return acct, nil
+}
+
+// accountForValidation returns the acme-dns account registered for the domain
+func (c *AcmednsClient) accountForValidation(domain string) (goacmedns.Account, error) {
+	acct, err := c.Storage.Fetch(domain)
+	if err != nil && err != goacmedns.ErrDomainNotFound {
+		return acct, fmt.Errorf("Validation failed: %s", err)
+	} else if err == goacmedns.ErrDomainNotFound {
+		return acct, classifiedError{ERROR_CLASS_ACCOUNT,
+			fmt.Errorf("Domain %s does not have acme-dns account registered for it. Validation failed.", domain)}
+	}
+	return acct, nil
+}
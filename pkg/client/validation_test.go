@@ -0,0 +1,177 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/acme-dns/acme-dns-client/pkg/integration"
+
+	"github.com/cpu/goacmedns"
+)
+
+// hookEnv lists the environment variables the integrations read the hook data from
+var hookEnv = []string{
+	"CERTBOT_DOMAIN", "CERTBOT_VALIDATION", "CERTBOT_AUTH_OUTPUT",
+	"ACMEDNS_DOMAIN", "ACMEDNS_TOKEN", "ACMEDNS_EVENT", "EXEC_MODE",
+}
+
+// setHookData sets the command line arguments and the environment variables for the duration of the test
+func setHookData(t *testing.T, args []string, env map[string]string) {
+	t.Helper()
+	origArgs := os.Args
+	os.Args = append([]string{"acme-dns-client"}, args...)
+	t.Cleanup(func() { os.Args = origArgs })
+	for _, name := range hookEnv {
+		orig, set := os.LookupEnv(name)
+		os.Unsetenv(name)
+		if value, ok := env[name]; ok {
+			os.Setenv(name, value)
+		}
+		t.Cleanup(func() {
+			if set {
+				os.Setenv(name, orig)
+			} else {
+				os.Unsetenv(name)
+			}
+		})
+	}
+}
+
+func digest(keyAuth string) string {
+	sum := sha256.Sum256([]byte(keyAuth))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestHookIntegrations(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		args   []string
+		env    map[string]string
+		client string
+		event  integration.HookEvent
+		reqs   []integration.ValidationRequest
+		err    string
+	}{
+		{name: "no hook data"},
+		{
+			name:   "certbot auth hook",
+			env:    map[string]string{"CERTBOT_DOMAIN": "example.org", "CERTBOT_VALIDATION": "tok"},
+			client: "Certbot", event: integration.HookDeploy,
+			reqs: []integration.ValidationRequest{{Domain: "example.org", Token: "tok"}},
+		},
+		{
+			name:   "certbot cleanup hook",
+			env:    map[string]string{"CERTBOT_DOMAIN": "example.org", "CERTBOT_VALIDATION": "tok", "CERTBOT_AUTH_OUTPUT": ""},
+			client: "Certbot", event: integration.HookCleanup,
+			reqs: []integration.ValidationRequest{{Domain: "example.org", Token: "tok"}},
+		},
+		{
+			name:   "lego without CNAME record",
+			args:   []string{"present", "_acme-challenge.example.org.", "tok"},
+			client: "lego", event: integration.HookDeploy,
+			reqs: []integration.ValidationRequest{{Domain: "example.org", Token: "tok"}},
+		},
+		{
+			name:   "lego following the CNAME record",
+			args:   []string{"cleanup", "abc.auth.example.net.", "tok"},
+			client: "lego", event: integration.HookCleanup,
+			reqs: []integration.ValidationRequest{{Domain: "example.org", Token: "tok"}},
+		},
+		{
+			name:   "lego raw mode",
+			args:   []string{"present", "--", "*.example.org", "tok", "tok.thumbprint"},
+			env:    map[string]string{"EXEC_MODE": "RAW"},
+			client: "lego", event: integration.HookDeploy,
+			reqs: []integration.ValidationRequest{{Domain: "example.org", Token: digest("tok.thumbprint")}},
+		},
+		{
+			name:   "dehydrated",
+			args:   []string{"deploy_challenge", "example.org", "file", "tok"},
+			client: "dehydrated", event: integration.HookDeploy,
+			reqs: []integration.ValidationRequest{{Domain: "example.org", Token: "tok"}},
+		},
+		{
+			name:   "dehydrated with HOOK_CHAIN",
+			args:   []string{"clean_challenge", "example.org", "file1", "tok1", "*.example.com", "file2", "tok2"},
+			client: "dehydrated", event: integration.HookCleanup,
+			reqs: []integration.ValidationRequest{{Domain: "example.org", Token: "tok1"}, {Domain: "example.com", Token: "tok2"}},
+		},
+		{
+			name:   "dehydrated other hook",
+			args:   []string{"deploy_cert", "example.org", "key.pem", "cert.pem", "fullchain.pem", "chain.pem", "1600000000"},
+			client: "dehydrated", event: integration.HookIgnored,
+		},
+		{
+			name:   "uacme",
+			args:   []string{"begin", "dns-01", "*.example.org", "tok", "LPJNul-wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ"},
+			client: "uacme", event: integration.HookDeploy,
+			reqs: []integration.ValidationRequest{{Domain: "example.org", Token: "LPJNul-wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ"}},
+		},
+		{
+			name:   "getssl",
+			args:   []string{"getssl", "del", "example.org", "tok"},
+			client: "getssl", event: integration.HookCleanup,
+			reqs: []integration.ValidationRequest{{Domain: "example.org", Token: "tok"}},
+		},
+		{
+			name:   "generic",
+			env:    map[string]string{"ACMEDNS_DOMAIN": "*.example.org", "ACMEDNS_TOKEN": "tok"},
+			client: "generic", event: integration.HookDeploy,
+			reqs: []integration.ValidationRequest{{Domain: "example.org", Token: "tok"}},
+		},
+		{
+			name:   "generic cleanup",
+			env:    map[string]string{"ACMEDNS_DOMAIN": "example.org", "ACMEDNS_TOKEN": "tok", "ACMEDNS_EVENT": "cleanup"},
+			client: "generic", event: integration.HookCleanup,
+			reqs: []integration.ValidationRequest{{Domain: "example.org", Token: "tok"}},
+		},
+		{
+			name: "ambiguous",
+			args: []string{"getssl", "add", "example.org", "tok"},
+			env:  map[string]string{"CERTBOT_DOMAIN": "example.org", "CERTBOT_VALIDATION": "tok"},
+			err:  "Certbot, getssl",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			setHookData(t, tc.args, tc.env)
+			c := newTestClient(t)
+			c.Storage.Put("example.org", goacmedns.Account{FullDomain: "abc.auth.example.net", SubDomain: "abc"})
+			intgr, err := c.DetectIntegration()
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) || !strings.Contains(err.Error(), "refusing to guess") {
+					t.Errorf("Expected an error for ambiguous hook data, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if tc.client == "" {
+				if intgr != nil {
+					t.Errorf("Expected no integration, got %s", intgr.Name())
+				}
+				return
+			}
+			if intgr == nil || intgr.Name() != tc.client {
+				t.Fatalf("Expected %s integration, got %v", tc.client, intgr)
+			}
+			if event := intgr.HookEvent(); event != tc.event {
+				t.Errorf("Expected hook event %d, got %d", tc.event, event)
+			}
+			if tc.reqs == nil {
+				return
+			}
+			reqs, err := c.FindValidations(intgr)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(reqs, tc.reqs) {
+				t.Errorf("Expected validation requests %v, got %v", tc.reqs, reqs)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/acme-dns/acme-dns-client/pkg/dnsclient"
 	"github.com/acme-dns/acme-dns-client/pkg/integration"
+	"github.com/acme-dns/acme-dns-client/pkg/records"
 )
 
 var (
@@ -18,11 +19,10 @@ record manually. For this you are going to need to look into how your client sto
 example CAA record below accordingly and add it to your DNS zone:
 ---------------------------
 
-%s.         IN    CAA    0 issue "letsencrypt.org; validationmethods=dns-01; accounturi=https://acme-v01.api.letsencrypt.org/acme/reg/ACCOUNTUID"
-%s.         IN    CAA    0 issuewild "letsencrypt.org; validationmethods=dns-01; accounturi=https://acme-v01.api.letsencrypt.org/acme/reg/ACCOUNTUID"
-
+%s
 ---------------------------
 `
+	CAA_EXAMPLE_VALUE = "letsencrypt.org; validationmethods=dns-01; accounturi=https://acme-v01.api.letsencrypt.org/acme/reg/ACCOUNTUID"
 )
 
 func (c *AcmednsClient) CNAMESetupWizard(domain string) bool {
@@ -32,7 +32,8 @@ func (c *AcmednsClient) CNAMESetupWizard(domain string) bool {
 		PrintError(fmt.Sprintf("Error while trying to fetch acme-dns account from storage: %s", err),0)
 		return false
 	}
-	fmt.Printf(CNAME_INFO, domain, acct.FullDomain, domain, acct.FullDomain)
+	Printf(CNAME_INFO, domain, acct.FullDomain,
+		c.formatRecords(domain, []records.Record{records.ChallengeCNAME(domain, acct.FullDomain)}))
 	c.Debug("Starting DNS monitoring for CNAME changes")
 	return c.monitorCNAMERecordChange(domain, acct.FullDomain)
 }
@@ -42,28 +43,28 @@ func (c *AcmednsClient) CAASetupWizard(domain string) bool {
 	if len(accts) > 0 {
 		PrintInfo(fmt.Sprintf("Found a total of %d ACME account(s) on this system:", len(accts)), 0)
 		for _, a := range accts {
-			recString, err := a.CAARecordString()
-			if err != nil {
-				c.Verbose(fmt.Sprintf("Error while generating CAA record string: %s", err))
+			if a.URI == "" {
+				Printf("  [%s] URI: unknown, not stored by the ACME client (%s)\n", a.Client, a.FilePath)
+			} else {
+				Printf("  [%s] URI: %s\n", a.Client, a.URI)
 			}
-			fmt.Printf("  [%s] URI: %s\n", a.Client, a.URI)
 			c.Verbose(fmt.Sprintf("  Contact: %s\n", a.Contact))
 			c.Verbose(fmt.Sprintf("  Filepath: %s\n", a.FilePath))
-			if recString != "" {
-				fmt.Printf("  CAA record info:\n    -----------------------------------------------\n\n")
-				fmt.Printf("    %s.             IN    CAA    0 issue %s\n", domain, recString)
-				fmt.Printf("    %s.             IN    CAA    0 issuewild %s\n\n", domain, recString)
+			recs := c.caaRecords(domain, []integration.ACMEAccount{a})
+			if len(recs) > 0 {
+				Printf("  CAA record info:\n    -----------------------------------------------\n\n")
+				Printf("%s\n", c.formatRecords(domain, recs))
 			}
-			fmt.Printf("    -----------------------------------------------\n")
+			Printf("    -----------------------------------------------\n")
 		}
-		fmt.Printf(CAA_SETTINGS)
+		Printf(CAA_SETTINGS)
 		return c.monitorCAARecordChange(domain)
 	} else {
-		fmt.Printf(CAA_INFO_ACCOUNT_NOTFOUND, domain)
+		Printf(CAA_INFO_ACCOUNT_NOTFOUND, c.formatRecords(domain, records.CAAPair(domain, CAA_EXAMPLE_VALUE)))
 		if YesNoPrompt("Do you want acme-dns-client to monitor for CAA record change?", false) {
 			return c.monitorCAARecordChange(domain)
 		}
-		fmt.Printf(`After creation, the configuration for the domain %s by issuing the following command: 
+		Printf(`After creation, the configuration for the domain %s by issuing the following command: 
     acme-dns-client check -d %s
 `, domain, domain)
 	}
@@ -71,9 +72,9 @@ func (c *AcmednsClient) CAASetupWizard(domain string) bool {
 }
 
 func (c *AcmednsClient) monitorCAARecordChange(domain string) bool {
-	fmt.Printf("Waiting for CAA record to be created for domain %s\n", domain)
-	fmt.Printf("Querying the authoritative nameserver every 15 seconds.\n\n")
-	dnsc := dnsclient.NewDNSClient(c.Config.DNSServer)
+	Printf("Waiting for CAA record to be created for domain %s\n", domain)
+	Printf("Querying the authoritative nameserver every 15 seconds.\n\n")
+	dnsc := c.dnsClient()
 	for {
 		newcaa, err := dnsc.GetCAA(domain)
 		if err != nil && err != dnsclient.ErrCAARecordNotFound {
@@ -89,13 +90,12 @@ func (c *AcmednsClient) monitorCAARecordChange(domain string) bool {
 		}
 		printPauseCounter(15)
 	}
-	return false
 }
 
 func (c *AcmednsClient) monitorCNAMERecordChange(domain string, target string) bool {
-	fmt.Printf("Waiting for CNAME record to be set up for domain %s\n", domain)
-	fmt.Printf("Querying the authoritative nameserver every 15 seconds.\n\n")
-	dnsc := dnsclient.NewDNSClient(c.Config.DNSServer)
+	Printf("Waiting for CNAME record to be set up for domain %s\n", domain)
+	Printf("Querying the authoritative nameserver every 15 seconds.\n\n")
+	dnsc := c.dnsClient()
 	oldcname, err := dnsc.GetCNAME(domain)
 	if err != nil && err != dnsclient.ErrCNAMERecordNotFound {
 		PrintError(fmt.Sprintf("Caught an error while trying to query for CNAME record: %s", err), 0)
@@ -107,8 +107,8 @@ func (c *AcmednsClient) monitorCNAMERecordChange(domain string, target string) b
 			PrintError(fmt.Sprintf("Caught an error while trying to query for CNAME record: %s", err), 0)
 			return false
 		}
-		if cname.Target != oldcname.Target {
-			c.Verbose(fmt.Sprintf("Detected a change in CNAME record. New CNAME target: %s", cname.Target))
+		if cname.FinalTarget() != oldcname.FinalTarget() {
+			c.Verbose(fmt.Sprintf("Detected a change in CNAME record. New CNAME chain: %s", cname.String()))
 			oldcname = cname
 		}
 		if cname.CorrectTarget(target) {
@@ -117,7 +117,6 @@ func (c *AcmednsClient) monitorCNAMERecordChange(domain string, target string) b
 		}
 		printPauseCounter(15)
 	}
-	return false
 }
 
 func (c *AcmednsClient) findACMEAccounts() []integration.ACMEAccount {
@@ -129,11 +128,11 @@ func (c *AcmednsClient) findACMEAccounts() []integration.ACMEAccount {
 			c.Verbose(fmt.Sprintf("Installation of ACME client %s found, looking for accounts", i.Name()))
 			accts, err := i.FindAccounts()
 			if err != nil {
-				c.Debug(fmt.Sprintf("Error while looking for %s ACME accounts: %s", i.Name(), err))
-			} else {
-				c.Verbose(fmt.Sprintf("Found %d account(s)", len(accts)))
-				acmeAccts = append(acmeAccts, accts...)
+				c.Verbose(fmt.Sprintf("Error while looking for %s ACME accounts: %s", i.Name(), err))
 			}
+			// The accounts found before an error are still usable
+			c.Verbose(fmt.Sprintf("Found %d account(s)", len(accts)))
+			acmeAccts = append(acmeAccts, accts...)
 		}
 		c.Debug(fmt.Sprintf("Looking for ACME accounts from %s configuration", i.Name()))
 	}
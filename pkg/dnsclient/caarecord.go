@@ -66,16 +66,7 @@ func (c *Client) CheckCAA(domain string) (CAACheckResult, error) {
 //GetCAA fetches the CAA records for a domain
 func (c *Client) GetCAA(domain string) ([]CAARecord, error) {
 	records := []CAARecord{}
-	msg := new(dns.Msg)
-	msg.SetQuestion(dns.Fqdn(domain), dns.TypeCAA)
-	msg.RecursionDesired = true
-
-	ns, err := c.GetAuthoritativeNS(domain)
-	if err != nil {
-		// Fallback to default nameserver
-		ns = c.Server
-	}
-	in, err := dns.Exchange(msg, ns)
+	in, err := c.authoritativeQuery(domain, dns.TypeCAA)
 
 	if err != nil {
 		return records, err
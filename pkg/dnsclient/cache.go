@@ -0,0 +1,45 @@
+package dnsclient
+
+import (
+	"sync"
+)
+
+// Cache holds the results of NS, SOA and address lookups for the duration of a single run, so that domains
+// sharing parent zones and name servers are not looked up again. Concurrent lookups of the same key wait for
+// the first one to complete. A nil Cache disables caching.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// NewCache returns an empty Cache
+func NewCache() *Cache {
+	return &Cache{entries: make(map[string]*cacheEntry)}
+}
+
+// get returns the cached result for the key, or calls lookup and caches its result
+func (c *Cache) get(key string, lookup func() (interface{}, error)) (interface{}, error) {
+	if c == nil {
+		return lookup()
+	}
+	c.mu.Lock()
+	e, exists := c.entries[key]
+	if !exists {
+		e = &cacheEntry{done: make(chan struct{})}
+		c.entries[key] = e
+	}
+	c.mu.Unlock()
+	if exists {
+		<-e.done
+		return e.value, e.err
+	}
+	e.value, e.err = lookup()
+	close(e.done)
+	return e.value, e.err
+}
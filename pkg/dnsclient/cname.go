@@ -2,6 +2,8 @@ package dnsclient
 
 import (
 	"fmt"
+	"strings"
+
 	"github.com/miekg/dns"
 )
 
@@ -9,10 +11,16 @@ var (
 	ErrCNAMERecordNotFound = fmt.Errorf("No CNAME record found")
 )
 
+// MAX_CNAME_HOPS limits the length of the alias chain followed from the challenge domain
+const MAX_CNAME_HOPS = 8
+
 type CNAMERecord struct {
 	Domain string
 	HasCNAME bool
+	// Target is the target of the first alias
 	Target string
+	// Chain holds the targets of all the CNAME and DNAME aliases followed, the last one being the final name
+	Chain []string
 }
 
 func NewCNAMERecord() CNAMERecord {
@@ -20,40 +28,92 @@ func NewCNAMERecord() CNAMERecord {
 		Domain: "",
 		HasCNAME: false,
 		Target: "",
+		Chain: []string{},
+	}
+}
+
+// FinalTarget returns the name the alias chain ends at
+func (r *CNAMERecord) FinalTarget() string {
+	if len(r.Chain) > 0 {
+		return r.Chain[len(r.Chain)-1]
 	}
+	return r.Target
+}
+
+// String returns the alias chain, eg. "_acme-challenge.example.org. -> a.example.net. -> b.auth.example.net."
+func (r *CNAMERecord) String() string {
+	return strings.Join(append([]string{r.Domain}, r.Chain...), " -> ")
 }
 
 // CorrectTarget returns true if the CNAME has been set correctly according to
-// acme-dns account.
+// acme-dns account, either directly or through a chain of aliases.
 func (r *CNAMERecord) CorrectTarget(acmednsdomain string) bool {
-	return dns.Fqdn(acmednsdomain) == dns.Fqdn(r.Target)
+	return r.HasCNAME && dns.Fqdn(acmednsdomain) == dns.Fqdn(r.FinalTarget())
 }
 
-//GetCNAME fetches the CNAME for ACME "magic" subdomain _acme-challenge for a domain
+//GetCNAME fetches the CNAME for ACME "magic" subdomain _acme-challenge for a domain, and follows the chain of
+//CNAME and DNAME aliases from it. Loops and chains longer than MAX_CNAME_HOPS are returned as errors along with
+//the chain found so far.
 func (c *Client) GetCNAME(domain string) (CNAMERecord, error) {
-	domain = "_acme-challenge." + domain
-	msg := new(dns.Msg)
-	msg.SetQuestion(dns.Fqdn(domain), dns.TypeCNAME)
-	msg.RecursionDesired = true
-	ns, err := c.GetAuthoritativeNS(domain)
-	if err != nil {
-		// Fallback to default nameserver
-		ns = c.Server
+	domain = dns.Fqdn("_acme-challenge." + domain)
+	record := NewCNAMERecord()
+	record.Domain = domain
+	seen := map[string]bool{strings.ToLower(domain): true}
+	name := domain
+	for {
+		var in *dns.Msg
+		var err error
+		if name == domain {
+			// The challenge domain is looked up from the name servers of the domain, to avoid asking the name
+			// servers of a delegated _acme-challenge subzone
+			in, err = c.zoneQuery(domain[len("_acme-challenge."):], name, dns.TypeCNAME)
+		} else {
+			in, err = c.authoritativeQuery(name, dns.TypeCNAME)
+		}
+		if err != nil {
+			return record, err
+		}
+		// The response may contain several hops of the chain, eg. from a recursive resolver
+		next := aliasTarget(in.Answer, name)
+		if next == "" {
+			break
+		}
+		for next != "" {
+			if seen[strings.ToLower(next)] {
+				return record, fmt.Errorf("CNAME loop detected at %s", next)
+			}
+			if len(record.Chain) >= MAX_CNAME_HOPS {
+				return record, fmt.Errorf("CNAME chain from %s exceeds %d hops", domain, MAX_CNAME_HOPS)
+			}
+			seen[strings.ToLower(next)] = true
+			record.Chain = append(record.Chain, next)
+			record.HasCNAME = true
+			record.Target = record.Chain[0]
+			name = next
+			next = aliasTarget(in.Answer, name)
+		}
 	}
-	in, err := dns.Exchange(msg, ns)
-	if err != nil {
-		return NewCNAMERecord(), err
+	if !record.HasCNAME {
+		return record, ErrCNAMERecordNotFound
 	}
-	for _, a := range in.Answer {
-		if cname, ok := a.(*dns.CNAME); ok {
-			return CNAMERecord{
-				Domain: dns.Fqdn(domain),
-				HasCNAME: true,
-				Target: dns.Fqdn(cname.Target),
-			}, nil
-		} else {
-			return NewCNAMERecord(), fmt.Errorf("Unexpected record returned with CNAME query to domain %s\n", domain)
+	return record, nil
+}
+
+// aliasTarget returns the target of the CNAME owned by the name, or the name synthesized from a DNAME owned by
+// one of its ancestors. An empty string is returned if the records do not alias the name.
+func aliasTarget(rrs []dns.RR, name string) string {
+	for _, rr := range rrs {
+		if cname, ok := rr.(*dns.CNAME); ok && strings.EqualFold(cname.Hdr.Name, name) {
+			return dns.Fqdn(cname.Target)
+		}
+	}
+	for _, rr := range rrs {
+		dname, ok := rr.(*dns.DNAME)
+		if !ok || strings.EqualFold(dname.Hdr.Name, name) || !dns.IsSubDomain(dname.Hdr.Name, name) {
+			continue
 		}
+		prefix := strings.TrimSuffix(name[:len(name)-len(dname.Hdr.Name)], ".")
+		return dns.Fqdn(prefix + "." + dns.Fqdn(dname.Target))
 	}
-	return NewCNAMERecord(), ErrCNAMERecordNotFound
-}
\ No newline at end of file
+	return ""
+}
@@ -0,0 +1,169 @@
+package dnsclient
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// ConflictKind is the kind of a record conflicting with the CNAME record of the challenge domain
+type ConflictKind int
+
+const (
+	// ConflictTXT is a TXT record at the challenge domain, eg. left over from a manual setup
+	ConflictTXT ConflictKind = iota
+	// ConflictAddress is an A or AAAA record at the challenge domain
+	ConflictAddress
+	// ConflictNS is a set of NS records next to the CNAME record of the challenge domain
+	ConflictNS
+	// ConflictWildcard means the records of the challenge domain are synthesized from a wildcard
+	ConflictWildcard
+	// ConflictEmptyNonTerminal means the challenge domain exists without any of the queried records, usually
+	// because of the names below it
+	ConflictEmptyNonTerminal
+)
+
+// conflictTypes are the record types queried at the challenge domain in addition to CNAME, as ANY queries are
+// refused by most name servers (RFC 8482)
+var conflictTypes = []uint16{dns.TypeTXT, dns.TypeA, dns.TypeAAAA, dns.TypeNS}
+
+// Conflict is a finding at the challenge domain that breaks or obscures its delegation to acme-dns
+type Conflict struct {
+	Kind ConflictKind
+	// Name is the challenge domain
+	Name string
+	// Type is the type of the conflicting records, if any
+	Type uint16
+	// Records holds the data of the conflicting records
+	Records []string
+	// Wildcard is the wildcard name the records are synthesized from, for ConflictWildcard
+	Wildcard string
+}
+
+// GetConflicts queries the challenge domain _acme-challenge of a domain for TXT, A, AAAA and NS records, which
+// cannot coexist with a CNAME record, and detects records synthesized from a wildcard and empty non-terminals.
+// A challenge domain delegated with NS records and no CNAME record has no conflicts.
+func (c *Client) GetConflicts(domain string) ([]Conflict, error) {
+	name := dns.Fqdn("_acme-challenge." + domain)
+	conflicts := make([]Conflict, 0)
+	found := make(map[uint16][]dns.RR)
+	exists := false
+	for _, qtype := range append([]uint16{dns.TypeCNAME}, conflictTypes...) {
+		// The conflicting records are in the zone of the domain, even if _acme-challenge is delegated
+		in, err := c.zoneQuery(domain, name, qtype)
+		if err != nil {
+			return conflicts, err
+		}
+		if in.Rcode == dns.RcodeSuccess {
+			exists = true
+		}
+		// NS records of a delegation are returned in the authority section of a referral
+		if rrs := ownerRecords(append(in.Answer, in.Ns...), name, qtype); len(rrs) > 0 {
+			found[qtype] = rrs
+		}
+	}
+	if _, cname := found[dns.TypeCNAME]; !cname && len(found[dns.TypeNS]) > 0 {
+		// A NS delegation of _acme-challenge is checked with GetDelegation, the records of the subzone are its own
+		return conflicts, nil
+	}
+	if len(found) == 0 {
+		if exists {
+			conflicts = append(conflicts, Conflict{Kind: ConflictEmptyNonTerminal, Name: name})
+		}
+		return conflicts, nil
+	}
+	wildcard, err := c.synthesizedFromWildcard(domain, name, found)
+	if err != nil {
+		return conflicts, err
+	}
+	if wildcard {
+		// The other findings would be about the wildcard records, only the wildcard is reported
+		records := make([]string, 0)
+		for _, qtype := range append([]uint16{dns.TypeCNAME}, conflictTypes...) {
+			data, _ := recordStrings(found[qtype])
+			records = append(records, data...)
+		}
+		return append(conflicts, Conflict{
+			Kind:     ConflictWildcard,
+			Name:     name,
+			Records:  records,
+			Wildcard: "*." + strings.SplitN(name, ".", 2)[1],
+		}), nil
+	}
+	for _, qtype := range conflictTypes {
+		rrs, ok := found[qtype]
+		if !ok {
+			continue
+		}
+		records, _ := recordStrings(rrs)
+		conflict := Conflict{Name: name, Type: qtype, Records: records}
+		switch qtype {
+		case dns.TypeTXT:
+			conflict.Kind = ConflictTXT
+		case dns.TypeA, dns.TypeAAAA:
+			conflict.Kind = ConflictAddress
+		case dns.TypeNS:
+			conflict.Kind = ConflictNS
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	return conflicts, nil
+}
+
+// synthesizedFromWildcard returns true if a random sibling of the name has the same records as found for the
+// name, in which case the records are most likely synthesized from a wildcard
+func (c *Client) synthesizedFromWildcard(domain string, name string, found map[uint16][]dns.RR) (bool, error) {
+	label := make([]byte, 8)
+	if _, err := rand.Read(label); err != nil {
+		return false, err
+	}
+	probe := "_acme-dns-client-" + hex.EncodeToString(label) + "." + strings.SplitN(name, ".", 2)[1]
+	for qtype, rrs := range found {
+		if qtype == dns.TypeNS {
+			// Wildcards do not apply to delegations
+			return false, nil
+		}
+		in, err := c.zoneQuery(domain, probe, qtype)
+		if err != nil {
+			return false, err
+		}
+		want, _ := recordStrings(rrs)
+		got, _ := recordStrings(ownerRecords(append(in.Answer, in.Ns...), probe, qtype))
+		if !sameRecords(want, got) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ownerRecords returns the records of the type owned by the name, leaving out the records of the names an alias
+// points to
+func ownerRecords(rrs []dns.RR, name string, qtype uint16) []dns.RR {
+	out := make([]dns.RR, 0)
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == qtype && strings.EqualFold(rr.Header().Name, name) {
+			out = append(out, rr)
+		}
+	}
+	return out
+}
+
+// sameRecords returns true if both have the same record data, in any order
+func sameRecords(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
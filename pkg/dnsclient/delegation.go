@@ -0,0 +1,117 @@
+package dnsclient
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/miekg/dns"
+)
+
+var (
+	ErrNotDelegated = fmt.Errorf("No NS delegation found")
+)
+
+// Delegation is the NS delegation of the challenge domain _acme-challenge straight to the acme-dns server, an
+// alternative to the CNAME record
+type Delegation struct {
+	Name        string
+	NameServers []string
+	// Servers holds the results of the checks made against each of the delegated name servers
+	Servers []DelegatedServer
+}
+
+// DelegatedServer is the result of checking a single delegated name server
+type DelegatedServer struct {
+	Host    string
+	Address string
+	// Authoritative is true if the server answered authoritatively for both the challenge domain and the
+	// acme-dns account domain
+	Authoritative bool
+	// Equivalent is true if the server answered the same TXT records for the challenge domain and the acme-dns
+	// account domain
+	Equivalent bool
+	Err        error
+}
+
+// IsDelegated returns true if the challenge domain is delegated with NS records
+func (d *Delegation) IsDelegated() bool {
+	return len(d.NameServers) > 0
+}
+
+// Working returns true if all the delegated name servers serve the challenge domain like the acme-dns account
+// domain
+func (d *Delegation) Working() bool {
+	if len(d.Servers) == 0 {
+		return false
+	}
+	for _, s := range d.Servers {
+		if !s.Working() {
+			return false
+		}
+	}
+	return true
+}
+
+// Working returns true if the name server answers for the challenge domain like for the acme-dns account domain
+func (s *DelegatedServer) Working() bool {
+	return s.Err == nil && s.Authoritative && s.Equivalent
+}
+
+//GetDelegation looks up the NS delegation of the challenge domain _acme-challenge of a domain from the name
+//servers of the domain, and checks that each delegated name server answers authoritatively for the acme-dns
+//account domain fulldomain, and returns the same TXT records for the challenge domain.
+func (c *Client) GetDelegation(domain string, fulldomain string) (Delegation, error) {
+	name := dns.Fqdn("_acme-challenge." + domain)
+	d := Delegation{
+		Name:        name,
+		NameServers: make([]string, 0),
+		Servers:     make([]DelegatedServer, 0),
+	}
+	in, err := c.zoneQuery(domain, name, dns.TypeNS)
+	if err != nil {
+		return d, err
+	}
+	if len(ownerRecords(in.Answer, name, dns.TypeCNAME)) > 0 {
+		return d, ErrNotDelegated
+	}
+	// The parent zone responds with a referral, a resolver with the NS records in the answer section
+	for _, rr := range ownerRecords(append(in.Answer, in.Ns...), name, dns.TypeNS) {
+		d.NameServers = append(d.NameServers, rr.(*dns.NS).Ns)
+	}
+	if len(d.NameServers) == 0 {
+		return d, ErrNotDelegated
+	}
+	for _, host := range d.NameServers {
+		addrs, err := c.lookupAddr(host)
+		if err != nil || len(addrs) == 0 {
+			d.Servers = append(d.Servers, DelegatedServer{Host: host, Err: fmt.Errorf("Could not find the address of %s", host)})
+			continue
+		}
+		d.Servers = append(d.Servers, c.checkDelegatedServer(host, net.JoinHostPort(addrs[0], "53"), name, dns.Fqdn(fulldomain)))
+	}
+	return d, nil
+}
+
+// checkDelegatedServer queries the name server for the TXT records of the challenge domain and the acme-dns
+// account domain without recursion, and compares the responses
+func (c *Client) checkDelegatedServer(host string, address string, name string, fulldomain string) DelegatedServer {
+	s := DelegatedServer{Host: host, Address: address, Authoritative: true}
+	responses := make([]*dns.Msg, 0, 2)
+	for _, qname := range []string{name, fulldomain} {
+		msg := new(dns.Msg)
+		msg.SetQuestion(qname, dns.TypeTXT)
+		in, err := c.exchange(msg, address)
+		if err != nil {
+			s.Err = err
+			return s
+		}
+		if !in.Authoritative {
+			s.Authoritative = false
+		}
+		responses = append(responses, in)
+	}
+	challenge, _ := recordStrings(ownerRecords(responses[0].Answer, name, dns.TypeTXT))
+	account, _ := recordStrings(ownerRecords(responses[1].Answer, fulldomain, dns.TypeTXT))
+	s.Equivalent = responses[0].Rcode == responses[1].Rcode && sameRecords(challenge, account)
+	return s
+}
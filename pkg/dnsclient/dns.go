@@ -4,27 +4,241 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// DEFAULT_TIMEOUT is the default timeout of a single DNS query
+	DEFAULT_TIMEOUT = 5 * time.Second
+	// DEFAULT_RETRIES is the default number of times a query is retried after a timeout or a network error
+	DEFAULT_RETRIES = 2
+	// DEFAULT_UDP_SIZE is the default EDNS0 UDP buffer size, chosen to avoid IP fragmentation
+	DEFAULT_UDP_SIZE = 1232
 )
 
 type Client struct {
+	// Servers are the recursive resolvers, tried in order until one of them responds
+	Servers []string
+	// Search and Ndots control the search list expansion of names, as in resolv.conf
+	Search []string
+	Ndots  int
+	// Timeout is the timeout of a single DNS query
+	Timeout time.Duration
+	// Retries is the number of times a query is retried after a timeout or a network error
+	Retries int
+	// UDPSize is the EDNS0 UDP buffer size advertised in the queries, 0 disables EDNS0
+	UDPSize uint16
+	// Transport holds the connections to the DNS-over-HTTPS and DNS-over-TLS resolvers, shared by the clients
+	// of a run. May be nil for the default certificate verification without connection reuse.
+	Transport *Transport
+	// Cache holds the NS, SOA and address lookup results shared between clients of the same run, may be nil
+	Cache *Cache
+}
+
+// RcodeError is returned when a name server responds with an error other than NXDOMAIN
+type RcodeError struct {
 	Server string
+	Name   string
+	Type   uint16
+	Rcode  int
+}
+
+func (e *RcodeError) Error() string {
+	return fmt.Sprintf("%s query for %s failed at %s: %s", dns.TypeToString[e.Type], e.Name, e.Server, dns.RcodeToString[e.Rcode])
+}
+
+// NewDNSClient returns a client using the resolvers, search list and options from the system configuration.
+// The resolvers are replaced with servers if any are given.
+func NewDNSClient(servers []string) *Client {
+	conf := SystemResolverConfig()
+	if len(servers) > 0 {
+		conf.Servers = servers
+	}
+	return &Client{
+		Servers: conf.Servers,
+		Search:  conf.Search,
+		Ndots:   conf.Ndots,
+		Timeout: conf.Timeout,
+		Retries: conf.Attempts - 1,
+		UDPSize: DEFAULT_UDP_SIZE,
+	}
+}
+
+// serverAddress returns the address of the server with the default port 53 added if it is missing. Both IPv4
+// and IPv6 addresses, with or without brackets, and host names are accepted.
+func serverAddress(server string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	host := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(server, "["), "]"), ".")
+	return net.JoinHostPort(host, "53")
+}
+
+// exchange sends the query to the server and returns the response. Plain DNS queries are sent over UDP with
+// EDNS0, and retried over TCP if the response is truncated. Servers given as https:// URLs are queried with
+// DNS-over-HTTPS and tls:// servers with DNS-over-TLS. Timeouts and network errors are retried, and error
+// rcodes other than NXDOMAIN are returned as RcodeError.
+func (c *Client) exchange(msg *dns.Msg, server string) (*dns.Msg, error) {
+	if c.UDPSize > 0 && msg.IsEdns0() == nil {
+		msg.SetEdns0(c.UDPSize, false)
+	}
+	var in *dns.Msg
+	var err error
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		in, err = c.exchangeOnce(msg, server)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if in.Rcode != dns.RcodeSuccess && in.Rcode != dns.RcodeNameError {
+		return in, &RcodeError{Server: server, Name: msg.Question[0].Name, Type: msg.Question[0].Qtype, Rcode: in.Rcode}
+	}
+	return in, nil
+}
+
+// exchangeOnce sends the query to the server using the transport selected by the server address
+func (c *Client) exchangeOnce(msg *dns.Msg, server string) (*dns.Msg, error) {
+	switch {
+	case strings.HasPrefix(server, "https://"):
+		return c.exchangeDoH(msg, server)
+	case strings.HasPrefix(server, "tls://"):
+		return c.exchangeDoT(msg, server)
+	}
+	server = serverAddress(server)
+	client := &dns.Client{Timeout: c.Timeout, UDPSize: c.UDPSize}
+	in, _, err := client.Exchange(msg, server)
+	if err == nil && in.Truncated {
+		tcp := &dns.Client{Net: "tcp", Timeout: c.Timeout}
+		in, _, err = tcp.Exchange(msg, server)
+	}
+	return in, err
+}
+
+// query sends the query to the configured resolvers with recursion desired, failing over to the next resolver
+// on network errors and error responses. The search list is applied to the question name, unless it is given as
+// an absolute name with a trailing dot. Names taken from DNS data must always be given as absolute names.
+func (c *Client) query(name string, qtype uint16) (*dns.Msg, error) {
+	var in *dns.Msg
+	var err error
+	for _, candidate := range c.nameCandidates(name) {
+		msg := new(dns.Msg)
+		msg.SetQuestion(candidate, qtype)
+		msg.RecursionDesired = true
+		in, err = c.exchangeResolvers(msg)
+		if err == nil && in.Rcode != dns.RcodeNameError {
+			return in, nil
+		}
+	}
+	return in, err
+}
+
+// exchangeResolvers sends the query to the configured resolvers in order, until one of them responds without
+// a network error or an error rcode
+func (c *Client) exchangeResolvers(msg *dns.Msg) (*dns.Msg, error) {
+	var in *dns.Msg
+	err := fmt.Errorf("No DNS resolvers configured")
+	for _, server := range c.Servers {
+		in, err = c.exchange(msg, server)
+		if err == nil {
+			break
+		}
+	}
+	return in, err
+}
+
+// authoritativeQuery sends the query to the first authoritative name server of the name, or to the configured
+// resolvers if the name server cannot be found or only encrypted resolvers are configured
+func (c *Client) authoritativeQuery(name string, qtype uint16) (*dns.Msg, error) {
+	return c.zoneQuery(name, name, qtype)
+}
+
+// zoneQuery works like authoritativeQuery, but sends the query to the first authoritative name server found for
+// zone instead. This allows querying the parent zone of a delegated name.
+func (c *Client) zoneQuery(zone string, name string, qtype uint16) (*dns.Msg, error) {
+	if c.encryptedOnly() {
+		// Plain DNS queries to the authoritative name servers are likely not allowed, if only encrypted
+		// resolvers are configured
+		return c.query(name, qtype)
+	}
+	ns, err := c.GetAuthoritativeNS(zone)
+	if err != nil {
+		return c.query(name, qtype)
+	}
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.RecursionDesired = true
+	return c.exchange(msg, ns)
+}
+
+// lookupNS returns the name server host names of the zone
+func (c *Client) lookupNS(zone string) ([]string, error) {
+	zone = dns.Fqdn(zone)
+	v, err := c.Cache.get("ns:"+strings.ToLower(zone), func() (interface{}, error) {
+		hosts := []string{}
+		in, err := c.query(zone, dns.TypeNS)
+		if err != nil {
+			return hosts, err
+		}
+		for _, rr := range in.Answer {
+			if ns, ok := rr.(*dns.NS); ok {
+				hosts = append(hosts, ns.Ns)
+			}
+		}
+		if len(hosts) == 0 {
+			return hosts, fmt.Errorf("No NS records found for %s", zone)
+		}
+		return hosts, nil
+	})
+	return v.([]string), err
 }
 
-func NewDNSClient(server string) *Client {
-	return &Client{Server: server}
+// lookupAddr returns the IPv4 and IPv6 addresses of the host, which is always looked up as an absolute name
+func (c *Client) lookupAddr(host string) ([]string, error) {
+	host = dns.Fqdn(host)
+	v, err := c.Cache.get("addr:"+strings.ToLower(host), func() (interface{}, error) {
+		addrs := []string{}
+		var lastErr error
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			in, err := c.query(host, qtype)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			for _, rr := range in.Answer {
+				switch a := rr.(type) {
+				case *dns.A:
+					addrs = append(addrs, a.A.String())
+				case *dns.AAAA:
+					addrs = append(addrs, a.AAAA.String())
+				}
+			}
+		}
+		if len(addrs) == 0 && lastErr != nil {
+			return addrs, lastErr
+		}
+		return addrs, nil
+	})
+	return v.([]string), err
 }
 
 //GetAuthoritativeNS returns the first authoritative name server (from NS records) of a domain
 func (c *Client) GetAuthoritativeNS(domain string) (string, error) {
-	dparts := strings.Split(domain, ".")
-	for i := 0; i < len(dparts) - 1 ; i++ {
-		nss, err := net.LookupNS(strings.Join(dparts[i:], "."))
+	dparts := strings.Split(strings.TrimSuffix(domain, "."), ".")
+	for i := 0; i < len(dparts)-1; i++ {
+		nss, err := c.lookupNS(strings.Join(dparts[i:], "."))
 		if err != nil {
 			continue
 		}
-		if len(nss) > 0 {
-			return nss[0].Host+":53", nil
+		addrs, err := c.lookupAddr(nss[0])
+		if err != nil || len(addrs) == 0 {
+			return serverAddress(nss[0]), nil
 		}
+		return net.JoinHostPort(addrs[0], "53"), nil
 	}
 	return "", fmt.Errorf("No nameservers found for domain %s", domain)
-}
\ No newline at end of file
+}
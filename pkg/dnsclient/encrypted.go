@@ -0,0 +1,233 @@
+package dnsclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// DOH_MEDIA_TYPE is the media type of DNS wire format messages in DNS-over-HTTPS (RFC 8484)
+const DOH_MEDIA_TYPE = "application/dns-message"
+
+// NewTLSConfig returns the TLS configuration for the encrypted resolvers. The server certificates are verified
+// against the CA certificates in the PEM file if one is given, otherwise against the system roots. With insecure,
+// the certificates are not verified at all.
+func NewTLSConfig(caFile string, insecure bool) (*tls.Config, error) {
+	conf := &tls.Config{InsecureSkipVerify: insecure}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read CA certificates: %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No CA certificates found in %s", caFile)
+		}
+		conf.RootCAs = pool
+	}
+	return conf, nil
+}
+
+// isEncrypted returns true if the server is a DNS-over-HTTPS or DNS-over-TLS resolver
+func isEncrypted(server string) bool {
+	return strings.HasPrefix(server, "https://") || strings.HasPrefix(server, "tls://")
+}
+
+// encryptedOnly returns true if all the configured resolvers are encrypted
+func (c *Client) encryptedOnly() bool {
+	for _, s := range c.Servers {
+		if !isEncrypted(s) {
+			return false
+		}
+	}
+	return len(c.Servers) > 0
+}
+
+// ENCRYPTED_IDLE_TIMEOUT is how long idle connections to the DNS-over-HTTPS and DNS-over-TLS resolvers are kept open
+const ENCRYPTED_IDLE_TIMEOUT = 30 * time.Second
+
+// Transport holds the connections to the encrypted resolvers, so that the queries of a run reuse them instead
+// of making a TLS handshake for each query. A Transport is safe for concurrent use.
+type Transport struct {
+	tls  *tls.Config
+	http *http.Client
+	mu   sync.Mutex
+	// idle holds the idle DNS-over-TLS connections by the resolver address
+	idle map[string][]*idleConn
+}
+
+type idleConn struct {
+	conn  *dns.Conn
+	since time.Time
+}
+
+// NewTransport returns a Transport verifying the resolver certificates with the TLS configuration, which may be
+// nil for the defaults
+func NewTransport(conf *tls.Config) *Transport {
+	if conf == nil {
+		conf = &tls.Config{}
+	}
+	return &Transport{
+		tls: conf,
+		http: &http.Client{
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				TLSClientConfig:     conf.Clone(),
+				ForceAttemptHTTP2:   true,
+				MaxIdleConnsPerHost: 10,
+				IdleConnTimeout:     ENCRYPTED_IDLE_TIMEOUT,
+			},
+		},
+		idle: make(map[string][]*idleConn),
+	}
+}
+
+// CloseIdleConnections closes the idle connections to the encrypted resolvers
+func (t *Transport) CloseIdleConnections() {
+	t.http.CloseIdleConnections()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for addr, conns := range t.idle {
+		for _, ic := range conns {
+			ic.conn.Close()
+		}
+		delete(t.idle, addr)
+	}
+}
+
+// tlsConfig returns a copy of the TLS configuration for the server name
+func (t *Transport) tlsConfig(serverName string) *tls.Config {
+	conf := t.tls.Clone()
+	if conf.ServerName == "" {
+		conf.ServerName = serverName
+	}
+	return conf
+}
+
+// getConn returns an idle DNS-over-TLS connection to the address, or nil if there is none
+func (t *Transport) getConn(addr string) *dns.Conn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for len(t.idle[addr]) > 0 {
+		conns := t.idle[addr]
+		ic := conns[len(conns)-1]
+		t.idle[addr] = conns[:len(conns)-1]
+		if time.Since(ic.since) < ENCRYPTED_IDLE_TIMEOUT {
+			return ic.conn
+		}
+		ic.conn.Close()
+	}
+	return nil
+}
+
+// putConn keeps the DNS-over-TLS connection to the address for reuse
+func (t *Transport) putConn(addr string, conn *dns.Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.idle[addr] = append(t.idle[addr], &idleConn{conn: conn, since: time.Now()})
+}
+
+// transport returns the Transport of the client, or a new one and a function closing its connections if the
+// client has none
+func (c *Client) transport() (*Transport, func()) {
+	if c.Transport != nil {
+		return c.Transport, func() {}
+	}
+	t := NewTransport(nil)
+	return t, t.CloseIdleConnections
+}
+
+// exchangeDoH sends the query to a DNS-over-HTTPS resolver using a POST request with the wire format message
+func (c *Client) exchangeDoH(msg *dns.Msg, server string) (*dns.Msg, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid DNS-over-HTTPS resolver %s: %s", server, err)
+	}
+	// RFC 8484 recommends the ID 0 for cache friendliness, the original ID is restored in the response
+	query := msg.Copy()
+	query.Id = 0
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(packed))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", DOH_MEDIA_TYPE)
+	req.Header.Set("Accept", DOH_MEDIA_TYPE)
+	t, done := c.transport()
+	defer done()
+	resp, err := t.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DNS-over-HTTPS resolver %s responded with HTTP status %d", server, resp.StatusCode)
+	}
+	in := new(dns.Msg)
+	err = in.Unpack(body)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid DNS-over-HTTPS response from %s: %s", server, err)
+	}
+	in.Id = msg.Id
+	return in, nil
+}
+
+// exchangeDoT sends the query to a DNS-over-TLS resolver, given as tls://host[:port] with the default port 853.
+// Idle connections to the resolver are reused, a new one is made if the reused one has been closed.
+func (c *Client) exchangeDoT(msg *dns.Msg, server string) (*dns.Msg, error) {
+	addr := strings.TrimSuffix(strings.TrimPrefix(server, "tls://"), "/")
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(strings.Trim(addr, "[]"), "853")
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	t, done := c.transport()
+	defer done()
+	client := &dns.Client{
+		Net:       "tcp-tls",
+		Timeout:   c.Timeout,
+		TLSConfig: t.tlsConfig(host),
+	}
+	if conn := t.getConn(addr); conn != nil {
+		in, _, err := client.ExchangeWithConn(msg, conn)
+		if err == nil {
+			t.putConn(addr, conn)
+			return in, nil
+		}
+		conn.Close()
+	}
+	conn, err := client.Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	in, _, err := client.ExchangeWithConn(msg, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	t.putConn(addr, conn)
+	return in, nil
+}
@@ -0,0 +1,214 @@
+package dnsclient
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// testAnswer returns a response to the query with a single A record
+func testAnswer(req *dns.Msg) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetReply(req)
+	rr, _ := dns.NewRR(req.Question[0].Name + " 60 IN A 192.0.2.1")
+	m.Answer = append(m.Answer, rr)
+	return m
+}
+
+// writeCAFile writes the certificate of the test server as a PEM file, for -ns-ca
+func writeCAFile(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "acmedns-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newDoHServer returns a DNS-over-HTTPS stand-in server. The query IDs seen by the server are sent to ids.
+func newDoHServer(t *testing.T, status int, ids chan<- uint16) *httptest.Server {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != DOH_MEDIA_TYPE {
+			t.Errorf("Unexpected DoH request: %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		req := new(dns.Msg)
+		if err := req.Unpack(body); err != nil {
+			t.Errorf("Could not unpack DoH query: %s", err)
+			return
+		}
+		if ids != nil {
+			ids <- req.Id
+		}
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		packed, _ := testAnswer(req).Pack()
+		w.Header().Set("Content-Type", DOH_MEDIA_TYPE)
+		w.Write(packed)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newEncryptedClient returns a client for the resolver with the certificate verification options
+func newEncryptedClient(t *testing.T, server string, caFile string, insecure bool) *Client {
+	t.Helper()
+	conf, err := NewTLSConfig(caFile, insecure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := NewTransport(conf)
+	t.Cleanup(transport.CloseIdleConnections)
+	return &Client{Servers: []string{server}, Timeout: 2 * time.Second, Transport: transport}
+}
+
+func testQuery(id uint16) *dns.Msg {
+	msg := new(dns.Msg)
+	msg.SetQuestion("example.org.", dns.TypeA)
+	msg.Id = id
+	return msg
+}
+
+func TestDoHCAFile(t *testing.T) {
+	ids := make(chan uint16, 1)
+	srv := newDoHServer(t, http.StatusOK, ids)
+	c := newEncryptedClient(t, srv.URL+"/dns-query", writeCAFile(t, srv), false)
+	in, err := c.exchangeOnce(testQuery(4242), c.Servers[0])
+	if err != nil {
+		t.Fatalf("DoH query with the CA file failed: %s", err)
+	}
+	if id := <-ids; id != 0 {
+		t.Errorf("Expected the DoH query ID to be 0, got %d", id)
+	}
+	if in.Id != 4242 {
+		t.Errorf("Expected the response ID to be restored to 4242, got %d", in.Id)
+	}
+	if len(in.Answer) != 1 {
+		t.Errorf("Expected a single answer, got %d", len(in.Answer))
+	}
+}
+
+func TestDoHVerification(t *testing.T) {
+	srv := newDoHServer(t, http.StatusOK, nil)
+	c := newEncryptedClient(t, srv.URL+"/dns-query", "", false)
+	if _, err := c.exchangeOnce(testQuery(1), c.Servers[0]); err == nil {
+		t.Error("Expected the self-signed certificate to be rejected without the CA file")
+	}
+	c = newEncryptedClient(t, srv.URL+"/dns-query", "", true)
+	if _, err := c.exchangeOnce(testQuery(1), c.Servers[0]); err != nil {
+		t.Errorf("Expected the DoH query to succeed with insecure, got: %s", err)
+	}
+}
+
+func TestDoHStatus(t *testing.T) {
+	srv := newDoHServer(t, http.StatusInternalServerError, nil)
+	c := newEncryptedClient(t, srv.URL+"/dns-query", "", true)
+	_, err := c.exchangeOnce(testQuery(1), c.Servers[0])
+	if err == nil || !strings.Contains(err.Error(), "HTTP status 500") {
+		t.Errorf("Expected an HTTP status error, got: %v", err)
+	}
+}
+
+// countingListener counts the accepted connections
+type countingListener struct {
+	net.Listener
+	accepted int32
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		atomic.AddInt32(&l.accepted, 1)
+	}
+	return conn, err
+}
+
+// newDoTServer returns a DNS-over-TLS stand-in server using the certificate of the HTTPS test server
+func newDoTServer(t *testing.T, certSrv *httptest.Server) (string, *countingListener) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counting := &countingListener{Listener: l}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	srv := &dns.Server{
+		Net:               "tcp-tls",
+		Listener:          tls.NewListener(counting, &tls.Config{Certificates: certSrv.TLS.Certificates}),
+		NotifyStartedFunc: wg.Done,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			w.WriteMsg(testAnswer(r))
+		}),
+	}
+	go srv.ActivateAndServe()
+	wg.Wait()
+	t.Cleanup(func() { srv.Shutdown() })
+	return "tls://" + l.Addr().String(), counting
+}
+
+func TestDoTCAFile(t *testing.T) {
+	certSrv := newDoHServer(t, http.StatusOK, nil)
+	server, listener := newDoTServer(t, certSrv)
+	c := newEncryptedClient(t, server, writeCAFile(t, certSrv), false)
+	for i := 0; i < 3; i++ {
+		in, err := c.exchangeOnce(testQuery(uint16(100+i)), server)
+		if err != nil {
+			t.Fatalf("DoT query with the CA file failed: %s", err)
+		}
+		if in.Id != uint16(100+i) || len(in.Answer) != 1 {
+			t.Errorf("Unexpected DoT response: %v", in)
+		}
+	}
+	if accepted := atomic.LoadInt32(&listener.accepted); accepted != 1 {
+		t.Errorf("Expected the DoT connection to be reused, got %d connections", accepted)
+	}
+}
+
+func TestDoTVerification(t *testing.T) {
+	certSrv := newDoHServer(t, http.StatusOK, nil)
+	server, _ := newDoTServer(t, certSrv)
+	c := newEncryptedClient(t, server, "", false)
+	if _, err := c.exchangeOnce(testQuery(1), server); err == nil {
+		t.Error("Expected the self-signed certificate to be rejected without the CA file")
+	}
+	c = newEncryptedClient(t, server, "", true)
+	if _, err := c.exchangeOnce(testQuery(1), server); err != nil {
+		t.Errorf("Expected the DoT query to succeed with insecure, got: %s", err)
+	}
+}
+
+func TestNewTLSConfigInvalidCAFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "acmedns-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ca.pem")
+	ioutil.WriteFile(path, []byte("not a certificate"), 0600)
+	if _, err := NewTLSConfig(path, false); err == nil {
+		t.Error("Expected an error for a CA file without certificates")
+	}
+	if _, err := NewTLSConfig(filepath.Join(dir, "missing.pem"), false); err == nil {
+		t.Error("Expected an error for a missing CA file")
+	}
+}
@@ -0,0 +1,74 @@
+package dnsclient
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// RESOLV_CONF is the system resolver configuration file
+	RESOLV_CONF = "/etc/resolv.conf"
+	// FALLBACK_SERVER is used when no resolvers are configured, eg. on Windows
+	FALLBACK_SERVER = "1.1.1.1:53"
+)
+
+// ResolverConfig holds the recursive resolvers and the name resolution options
+type ResolverConfig struct {
+	Servers []string
+	Search  []string
+	Ndots   int
+	Timeout time.Duration
+	// Attempts is the total number of times a query is sent to a single server
+	Attempts int
+}
+
+// SystemResolverConfig returns the resolver configuration from /etc/resolv.conf. If the file cannot be read
+// or does not list any name servers, FALLBACK_SERVER is used with the default options.
+func SystemResolverConfig() ResolverConfig {
+	conf := ResolverConfig{
+		Servers:  []string{},
+		Search:   []string{},
+		Ndots:    1,
+		Timeout:  DEFAULT_TIMEOUT,
+		Attempts: DEFAULT_RETRIES + 1,
+	}
+	cc, err := dns.ClientConfigFromFile(RESOLV_CONF)
+	if err == nil {
+		for _, s := range cc.Servers {
+			conf.Servers = append(conf.Servers, net.JoinHostPort(s, cc.Port))
+		}
+		conf.Search = cc.Search
+		conf.Ndots = cc.Ndots
+		if cc.Timeout > 0 {
+			conf.Timeout = time.Duration(cc.Timeout) * time.Second
+		}
+		if cc.Attempts > 0 {
+			conf.Attempts = cc.Attempts
+		}
+	}
+	if len(conf.Servers) == 0 {
+		conf.Servers = []string{FALLBACK_SERVER}
+	}
+	return conf
+}
+
+// nameCandidates returns the names to query for the name in order, applying the search list like the system
+// resolver does: names with fewer dots than ndots are tried with the search domains first, and the others as
+// is first. Absolute names, given with a trailing dot, are never expanded with the search list.
+func (c *Client) nameCandidates(name string) []string {
+	if len(c.Search) == 0 || dns.IsFqdn(name) {
+		return []string{dns.Fqdn(name)}
+	}
+	name = dns.Fqdn(name)
+	searched := make([]string, 0, len(c.Search))
+	for _, s := range c.Search {
+		searched = append(searched, dns.Fqdn(strings.TrimSuffix(name, ".")+"."+strings.Trim(s, ".")))
+	}
+	if strings.Count(strings.TrimSuffix(name, "."), ".") >= c.Ndots {
+		return append([]string{name}, searched...)
+	}
+	return append(searched, name)
+}
@@ -0,0 +1,399 @@
+package dnsclient
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// maxTraceHops limits the number of referrals followed for a single name
+	maxTraceHops = 20
+	// maxTraceCNAMEs limits the number of CNAMEs followed in a trace
+	maxTraceCNAMEs = 8
+	// maxTraceDepth limits the nested resolution of name server addresses missing glue
+	maxTraceDepth = 4
+)
+
+// tracePort is the port the name servers are queried on
+var tracePort = "53"
+
+// rootHints are the root name servers the iterative resolution starts from
+var rootHints = []traceServer{
+	{"a.root-servers.net.", []string{"198.41.0.4"}},
+	{"b.root-servers.net.", []string{"170.247.170.2"}},
+	{"c.root-servers.net.", []string{"192.33.4.12"}},
+	{"d.root-servers.net.", []string{"199.7.91.13"}},
+	{"e.root-servers.net.", []string{"192.203.230.10"}},
+	{"f.root-servers.net.", []string{"192.5.5.241"}},
+	{"g.root-servers.net.", []string{"192.112.36.4"}},
+	{"h.root-servers.net.", []string{"198.97.190.53"}},
+	{"i.root-servers.net.", []string{"192.36.148.17"}},
+	{"j.root-servers.net.", []string{"192.58.128.30"}},
+	{"k.root-servers.net.", []string{"193.0.14.129"}},
+	{"l.root-servers.net.", []string{"199.7.83.42"}},
+	{"m.root-servers.net.", []string{"202.12.27.33"}},
+}
+
+// HopKind is the outcome of a single query in a trace
+type HopKind int
+
+const (
+	HopReferral HopKind = iota
+	HopAnswer
+	HopCNAME
+	HopNXDomain
+	HopNoData
+	HopLame
+	HopError
+)
+
+func (k HopKind) String() string {
+	switch k {
+	case HopReferral:
+		return "referral"
+	case HopAnswer:
+		return "answer"
+	case HopCNAME:
+		return "CNAME"
+	case HopNXDomain:
+		return "NXDOMAIN"
+	case HopNoData:
+		return "no data"
+	case HopLame:
+		return "lame delegation"
+	}
+	return "error"
+}
+
+// TraceHop is a single query sent during the iterative resolution
+type TraceHop struct {
+	Name string
+	Type uint16
+	// Zone is the zone the server was expected to be authoritative for
+	Zone    string
+	Server  string
+	Address string
+	Latency time.Duration
+	Kind    HopKind
+	// Records are the referral name servers, or the answer records
+	Records []string
+	TTL     uint32
+	Err     error
+	// Issues are the problems found with the delegation, like glue mismatches
+	Issues []string
+}
+
+// Trace is the result of an iterative resolution
+type Trace struct {
+	Hops   []TraceHop
+	Answer []dns.RR
+}
+
+type traceServer struct {
+	name  string
+	addrs []string
+}
+
+// Trace resolves the name iteratively, starting from the root name servers and following the referrals and
+// CNAMEs, without using any recursive resolver. Every query sent is recorded as a hop of the trace.
+func (c *Client) Trace(name string, qtype uint16) (*Trace, error) {
+	t := &Trace{}
+	name = dns.Fqdn(name)
+	seen := map[string]bool{}
+	for i := 0; i < maxTraceCNAMEs; i++ {
+		seen[strings.ToLower(name)] = true
+		answer, next, err := c.traceName(t, name, qtype, 0)
+		t.Answer = append(t.Answer, answer...)
+		if err != nil || next == "" {
+			return t, err
+		}
+		if seen[strings.ToLower(next)] {
+			return t, fmt.Errorf("CNAME loop detected at %s", next)
+		}
+		name = next
+	}
+	return t, fmt.Errorf("Too many CNAMEs while tracing %s", name)
+}
+
+// traceName follows the referrals from the root to the answer for the name. If the answer is a CNAME, its target
+// is returned for the caller to follow.
+func (c *Client) traceName(t *Trace, name string, qtype uint16, depth int) ([]dns.RR, string, error) {
+	zone := "."
+	servers := rootHints
+	for i := 0; i < maxTraceHops; i++ {
+		resp, hop := c.askServers(t, name, qtype, zone, servers)
+		if resp == nil {
+			return nil, "", fmt.Errorf("None of the name servers of %s responded", zone)
+		}
+		switch hop.Kind {
+		case HopAnswer:
+			return answerRecords(resp, name), "", nil
+		case HopCNAME:
+			answer := answerRecords(resp, name)
+			for _, rr := range answer {
+				if cname, ok := rr.(*dns.CNAME); ok {
+					return answer, dns.Fqdn(cname.Target), nil
+				}
+			}
+			return answer, "", nil
+		case HopReferral:
+			child, nss := referral(resp, zone, name)
+			servers = c.referralServers(t, hop, child, nss, resp.Extra, depth)
+			if len(servers) == 0 {
+				return nil, "", fmt.Errorf("Could not find the addresses of the name servers of %s", child)
+			}
+			zone = child
+			continue
+		}
+		return nil, "", nil
+	}
+	return nil, "", fmt.Errorf("Too many referrals while tracing %s", name)
+}
+
+// askServers queries the servers of the zone in order until one of them gives a usable response. Every query is
+// recorded in the trace, and the last recorded hop is returned along with the response.
+func (c *Client) askServers(t *Trace, name string, qtype uint16, zone string, servers []traceServer) (*dns.Msg, *TraceHop) {
+	for _, s := range servers {
+		for _, addr := range s.addrs {
+			msg := new(dns.Msg)
+			msg.SetQuestion(name, qtype)
+			msg.RecursionDesired = false
+			start := time.Now()
+			in, err := c.exchange(msg, net.JoinHostPort(addr, tracePort))
+			hop := TraceHop{
+				Name:    name,
+				Type:    qtype,
+				Zone:    zone,
+				Server:  s.name,
+				Address: addr,
+				Latency: time.Since(start),
+			}
+			if _, rcodeErr := err.(*RcodeError); err != nil && !rcodeErr {
+				hop.Kind = HopError
+				hop.Err = err
+				t.Hops = append(t.Hops, hop)
+				continue
+			}
+			classifyHop(&hop, in, zone)
+			t.Hops = append(t.Hops, hop)
+			if hop.Kind == HopLame {
+				continue
+			}
+			return in, &t.Hops[len(t.Hops)-1]
+		}
+	}
+	return nil, nil
+}
+
+// classifyHop sets the kind, records and TTL of the hop from the response. A server that does not answer
+// authoritatively for the zone it was delegated, or refers back up the tree, is a lame delegation.
+func classifyHop(hop *TraceHop, in *dns.Msg, zone string) {
+	if in.Rcode != dns.RcodeSuccess && in.Rcode != dns.RcodeNameError {
+		hop.Kind = HopLame
+		hop.Issues = append(hop.Issues, fmt.Sprintf("%s responded %s for %s", hop.Server, dns.RcodeToString[in.Rcode], zone))
+		return
+	}
+	answer := answerRecords(in, hop.Name)
+	child, nss := referral(in, zone, hop.Name)
+	switch {
+	case len(answer) > 0:
+		hop.Kind = HopAnswer
+		for _, rr := range answer {
+			if rr.Header().Rrtype == dns.TypeCNAME && hop.Type != dns.TypeCNAME {
+				hop.Kind = HopCNAME
+			}
+		}
+		hop.Records, hop.TTL = recordStrings(answer)
+	case child != "":
+		hop.Kind = HopReferral
+		hop.Records, hop.TTL = recordStrings(nss)
+		return
+	case in.Rcode == dns.RcodeNameError:
+		hop.Kind = HopNXDomain
+	default:
+		hop.Kind = HopNoData
+	}
+	if !in.Authoritative {
+		hop.Kind = HopLame
+		hop.Issues = append(hop.Issues, fmt.Sprintf("%s is not authoritative for %s", hop.Server, zone))
+	}
+}
+
+// answerRecords returns the answer records owned by the name
+func answerRecords(in *dns.Msg, name string) []dns.RR {
+	rrs := make([]dns.RR, 0)
+	for _, rr := range in.Answer {
+		if strings.EqualFold(rr.Header().Name, name) {
+			rrs = append(rrs, rr)
+		}
+	}
+	return rrs
+}
+
+// referral returns the child zone and its NS records if the response delegates the name further down from zone
+func referral(in *dns.Msg, zone string, name string) (string, []dns.RR) {
+	nss := make([]dns.RR, 0)
+	child := ""
+	for _, rr := range in.Ns {
+		ns, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+		owner := ns.Hdr.Name
+		if strings.EqualFold(owner, zone) || !dns.IsSubDomain(zone, owner) || !dns.IsSubDomain(owner, name) {
+			continue
+		}
+		if child == "" {
+			child = owner
+		}
+		if strings.EqualFold(owner, child) {
+			nss = append(nss, ns)
+		}
+	}
+	return child, nss
+}
+
+// referralServers returns the name servers of the referral with their addresses, from the glue records or by
+// resolving them iteratively. In-bailiwick glue is compared to the address records in the child zone, and
+// mismatches are recorded as issues of the referral hop.
+func (c *Client) referralServers(t *Trace, hop *TraceHop, child string, nss []dns.RR, extra []dns.RR, depth int) []traceServer {
+	servers := make([]traceServer, 0)
+	for _, rr := range nss {
+		host := rr.(*dns.NS).Ns
+		glue := glueAddresses(host, extra)
+		addrs := glue
+		if len(addrs) == 0 && depth < maxTraceDepth {
+			addrs = c.traceAddresses(host, depth+1)
+		}
+		if len(addrs) > 0 {
+			servers = append(servers, traceServer{name: host, addrs: addrs})
+		}
+	}
+	for _, s := range servers {
+		if !dns.IsSubDomain(child, s.name) {
+			continue
+		}
+		glue := glueAddresses(s.name, extra)
+		if len(glue) == 0 {
+			hop.Issues = append(hop.Issues, fmt.Sprintf("No glue for in-zone name server %s", s.name))
+			continue
+		}
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			glueFamily := filterFamily(glue, qtype)
+			if len(glueFamily) == 0 {
+				continue
+			}
+			actual, ok := c.childAddresses(s.name, qtype, child, servers)
+			if ok && len(actual) == 0 {
+				hop.Issues = append(hop.Issues, fmt.Sprintf("Glue for %s (%s) exists, but it has no %s records in %s",
+					s.name, strings.Join(glueFamily, ", "), dns.TypeToString[qtype], child))
+			} else if ok && !sameAddresses(glueFamily, actual) {
+				hop.Issues = append(hop.Issues, fmt.Sprintf("Glue for %s (%s) does not match its %s records in %s (%s)",
+					s.name, strings.Join(glueFamily, ", "), dns.TypeToString[qtype], child, strings.Join(actual, ", ")))
+			}
+		}
+	}
+	return servers
+}
+
+// glueAddresses returns the IPv4 and IPv6 glue addresses of the host, IPv4 first
+func glueAddresses(host string, extra []dns.RR) []string {
+	owned := make([]dns.RR, 0)
+	for _, rr := range extra {
+		if strings.EqualFold(rr.Header().Name, host) {
+			owned = append(owned, rr)
+		}
+	}
+	addrs := addressStrings(owned)
+	return append(filterFamily(addrs, dns.TypeA), filterFamily(addrs, dns.TypeAAAA)...)
+}
+
+// childAddresses asks the child zone name servers for the addresses of the host of the type A or AAAA
+func (c *Client) childAddresses(host string, qtype uint16, child string, servers []traceServer) ([]string, bool) {
+	resp, _ := c.askServers(&Trace{}, host, qtype, child, servers)
+	if resp == nil {
+		return nil, false
+	}
+	return filterFamily(addressStrings(answerRecords(resp, host)), qtype), true
+}
+
+// addressStrings returns the addresses of the A and AAAA records
+func addressStrings(rrs []dns.RR) []string {
+	addrs := make([]string, 0)
+	for _, rr := range rrs {
+		switch a := rr.(type) {
+		case *dns.A:
+			addrs = append(addrs, a.A.String())
+		case *dns.AAAA:
+			addrs = append(addrs, a.AAAA.String())
+		}
+	}
+	return addrs
+}
+
+// filterFamily returns the IPv4 addresses for type A, and the IPv6 addresses for type AAAA
+func filterFamily(addrs []string, qtype uint16) []string {
+	out := make([]string, 0)
+	for _, a := range addrs {
+		ip := net.ParseIP(a)
+		if ip == nil {
+			continue
+		}
+		if (ip.To4() != nil) == (qtype == dns.TypeA) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// traceAddresses resolves the IPv4 addresses of a name server iteratively, without recording the hops
+func (c *Client) traceAddresses(host string, depth int) []string {
+	addrs := make([]string, 0)
+	name := dns.Fqdn(host)
+	for i := 0; i < maxTraceCNAMEs; i++ {
+		answer, next, err := c.traceName(&Trace{}, name, dns.TypeA, depth)
+		if err != nil {
+			return addrs
+		}
+		addrs = append(addrs, filterFamily(addressStrings(answer), dns.TypeA)...)
+		if next == "" {
+			return addrs
+		}
+		name = next
+	}
+	return addrs
+}
+
+// recordStrings returns the records in presentation format and their minimum TTL
+func recordStrings(rrs []dns.RR) ([]string, uint32) {
+	out := make([]string, 0, len(rrs))
+	var ttl uint32
+	for i, rr := range rrs {
+		out = append(out, strings.TrimPrefix(rr.String()[len(rr.Header().String()):], " "))
+		if i == 0 || rr.Header().Ttl < ttl {
+			ttl = rr.Header().Ttl
+		}
+	}
+	return out, ttl
+}
+
+func sameAddresses(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if net.ParseIP(a[i]).String() != net.ParseIP(b[i]).String() {
+			return false
+		}
+	}
+	return true
+}
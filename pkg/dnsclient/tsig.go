@@ -0,0 +1,78 @@
+package dnsclient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+var (
+	bindKeyName      = regexp.MustCompile(`key\s+"?([^"\s{]+)"?\s*{`)
+	bindKeyAlgorithm = regexp.MustCompile(`algorithm\s+"?([^";\s]+)"?\s*;`)
+	bindKeySecret    = regexp.MustCompile(`secret\s+"([^"]+)"\s*;`)
+
+	tsigAlgorithms = map[string]string{
+		"hmac-md5":    dns.HmacMD5,
+		"hmac-sha1":   dns.HmacSHA1,
+		"hmac-sha224": dns.HmacSHA224,
+		"hmac-sha256": dns.HmacSHA256,
+		"hmac-sha384": dns.HmacSHA384,
+		"hmac-sha512": dns.HmacSHA512,
+	}
+)
+
+// TSIGKey is a shared secret used to authenticate dynamic DNS updates
+type TSIGKey struct {
+	Name      string
+	Algorithm string
+	Secret    string
+}
+
+// ParseTSIGKeyFile reads a TSIG key from a file. Both the BIND key file format (as generated by tsig-keygen
+// and used by Knot) and the nsupdate -y style "[algorithm:]name:secret" format are supported.
+func ParseTSIGKeyFile(pth string) (TSIGKey, error) {
+	data, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return TSIGKey{}, err
+	}
+	return ParseTSIGKey(string(data))
+}
+
+// ParseTSIGKey parses a TSIG key definition
+func ParseTSIGKey(input string) (TSIGKey, error) {
+	input = strings.TrimSpace(input)
+	if m := bindKeyName.FindStringSubmatch(input); m != nil {
+		key := TSIGKey{Name: m[1], Algorithm: "hmac-md5"}
+		if alg := bindKeyAlgorithm.FindStringSubmatch(input); alg != nil {
+			key.Algorithm = alg[1]
+		}
+		secret := bindKeySecret.FindStringSubmatch(input)
+		if secret == nil {
+			return TSIGKey{}, fmt.Errorf("No secret found in TSIG key %s", key.Name)
+		}
+		key.Secret = secret[1]
+		return key.normalize()
+	}
+	fields := strings.Split(input, ":")
+	switch len(fields) {
+	case 2:
+		return TSIGKey{Name: fields[0], Algorithm: "hmac-md5", Secret: fields[1]}.normalize()
+	case 3:
+		return TSIGKey{Name: fields[1], Algorithm: fields[0], Secret: fields[2]}.normalize()
+	}
+	return TSIGKey{}, fmt.Errorf("Could not parse TSIG key, expected a BIND key file or [algorithm:]name:secret")
+}
+
+// normalize converts the key name and algorithm to the form expected by miekg/dns
+func (k TSIGKey) normalize() (TSIGKey, error) {
+	alg, ok := tsigAlgorithms[strings.TrimSuffix(strings.ToLower(k.Algorithm), ".")]
+	if !ok {
+		return TSIGKey{}, fmt.Errorf("Unsupported TSIG algorithm: %s", k.Algorithm)
+	}
+	k.Algorithm = alg
+	k.Name = dns.Fqdn(strings.ToLower(k.Name))
+	return k, nil
+}
@@ -0,0 +1,30 @@
+package dnsclient
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+var (
+	ErrTXTRecordNotFound = fmt.Errorf("No TXT record found")
+)
+
+//GetTXT fetches the TXT record values of a domain from its authoritative name server
+func (c *Client) GetTXT(domain string) ([]string, error) {
+	values := make([]string, 0)
+	in, err := c.authoritativeQuery(domain, dns.TypeTXT)
+	if err != nil {
+		return values, err
+	}
+	for _, a := range in.Answer {
+		if txt, ok := a.(*dns.TXT); ok {
+			values = append(values, strings.Join(txt.Txt, ""))
+		}
+	}
+	if len(values) == 0 {
+		return values, ErrTXTRecordNotFound
+	}
+	return values, nil
+}
@@ -0,0 +1,125 @@
+package dnsclient
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/acme-dns/acme-dns-client/pkg/records"
+
+	"github.com/miekg/dns"
+)
+
+// Zone holds the zone apex and the primary name server of a zone, as found from its SOA record
+type Zone struct {
+	Name    string
+	Primary string
+}
+
+// FindZone discovers the zone a name belongs to by looking up SOA records, starting from the name itself
+// and walking up the tree.
+func (c *Client) FindZone(name string) (Zone, error) {
+	labels := dns.SplitDomainName(name)
+	for i := range labels {
+		candidate := dns.Fqdn(strings.Join(labels[i:], "."))
+		in, err := c.querySOA(candidate)
+		if err != nil {
+			return Zone{}, err
+		}
+		if soa := zoneSOA(in, dns.Fqdn(name)); soa != nil {
+			return Zone{
+				Name:    dns.Fqdn(soa.Hdr.Name),
+				Primary: serverAddress(soa.Ns),
+			}, nil
+		}
+	}
+	return Zone{}, fmt.Errorf("Could not find the zone for %s", name)
+}
+
+// zoneSOA returns the SOA record of the zone of the name from the response, found from the answer section, or
+// from the authority section of a negative response. Answer records after a CNAME belong to the alias target,
+// and SOA records not owned by the name or one of its ancestors belong to another zone, so they are ignored.
+func zoneSOA(in *dns.Msg, name string) *dns.SOA {
+	for _, rr := range in.Answer {
+		if rr.Header().Rrtype == dns.TypeCNAME {
+			// The rest of the response is about the alias target, the zone is found from a parent name
+			return nil
+		}
+		if soa, ok := rr.(*dns.SOA); ok && dns.IsSubDomain(soa.Hdr.Name, name) {
+			return soa
+		}
+	}
+	for _, rr := range in.Ns {
+		if soa, ok := rr.(*dns.SOA); ok && dns.IsSubDomain(soa.Hdr.Name, name) {
+			return soa
+		}
+	}
+	return nil
+}
+
+// querySOA sends a SOA query for the name to the configured resolvers
+func (c *Client) querySOA(name string) (*dns.Msg, error) {
+	v, err := c.Cache.get("soa:"+strings.ToLower(name), func() (interface{}, error) {
+		msg := new(dns.Msg)
+		msg.SetQuestion(name, dns.TypeSOA)
+		msg.RecursionDesired = true
+		return c.exchangeResolvers(msg)
+	})
+	in, _ := v.(*dns.Msg)
+	return in, err
+}
+
+// NewUpdateMessage creates a RFC 2136 dynamic update message for the zone. When replace is true, the existing
+// RRsets with the same name and type are removed before adding the records.
+func NewUpdateMessage(zone string, recs []records.Record, replace bool) (*dns.Msg, error) {
+	msg := new(dns.Msg)
+	msg.SetUpdate(dns.Fqdn(zone))
+	rrs := make([]dns.RR, 0)
+	rrsets := make([]dns.RR, 0)
+	for _, r := range recs {
+		rr, err := r.RR()
+		if err != nil {
+			return nil, fmt.Errorf("Could not create %s record for %s: %s", r.Type, r.Name, err)
+		}
+		if !dns.IsSubDomain(dns.Fqdn(zone), rr.Header().Name) {
+			return nil, fmt.Errorf("Record %s is not in zone %s", rr.Header().Name, zone)
+		}
+		if !sameRRset(rrsets, rr) {
+			rrsets = append(rrsets, rr)
+		}
+		rrs = append(rrs, rr)
+	}
+	if replace {
+		msg.RemoveRRset(rrsets)
+	}
+	msg.Insert(rrs)
+	return msg, nil
+}
+
+// SendUpdate signs the dynamic update message with the TSIG key and sends it to the server
+func SendUpdate(msg *dns.Msg, server string, key TSIGKey) error {
+	client := new(dns.Client)
+	client.Net = "tcp"
+	client.Timeout = 10 * time.Second
+	client.TsigSecret = map[string]string{key.Name: key.Secret}
+	msg.SetTsig(key.Name, key.Algorithm, 300, time.Now().Unix())
+	server = serverAddress(server)
+	in, _, err := client.Exchange(msg, server)
+	if err != nil {
+		return err
+	}
+	if in.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("Dynamic update was refused by %s: %s", server, dns.RcodeToString[in.Rcode])
+	}
+	return nil
+}
+
+// sameRRset returns true if a record with the same owner name and type as rr exists in rrs
+func sameRRset(rrs []dns.RR, rr dns.RR) bool {
+	for _, r := range rrs {
+		if strings.EqualFold(r.Header().Name, rr.Header().Name) && r.Header().Rrtype == rr.Header().Rrtype {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package dnsprovider
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/acme-dns/acme-dns-client/pkg/records"
+
+	"github.com/miekg/dns"
+)
+
+// CloudflareProvider manages records using the Cloudflare API v4.
+// Configuration: CLOUDFLARE_API_TOKEN and optionally CLOUDFLARE_API_URL
+type CloudflareProvider struct {
+	APIURL   string
+	APIToken string
+}
+
+type cloudflareResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+type cloudflareZone struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type cloudflareDNSRecord struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Name    string          `json:"name"`
+	Content string          `json:"content,omitempty"`
+	Data    *cloudflareData `json:"data,omitempty"`
+	TTL     int             `json:"ttl"`
+}
+
+type cloudflareData struct {
+	Flags uint8  `json:"flags"`
+	Tag   string `json:"tag"`
+	Value string `json:"value"`
+}
+
+// NewCloudflareProvider returns a new CloudflareProvider instance
+func NewCloudflareProvider(creds Credentials) (DNSProvider, error) {
+	if err := creds.require("CLOUDFLARE_API_TOKEN"); err != nil {
+		return nil, err
+	}
+	return &CloudflareProvider{
+		APIURL:   strings.TrimSuffix(creds.GetDefault("CLOUDFLARE_API_URL", "https://api.cloudflare.com/client/v4"), "/"),
+		APIToken: creds.Get("CLOUDFLARE_API_TOKEN"),
+	}, nil
+}
+
+func (p *CloudflareProvider) Name() string {
+	return "Cloudflare"
+}
+
+func (p *CloudflareProvider) request(method string, pth string, body interface{}, result interface{}) error {
+	resp := struct {
+		cloudflareResponse
+		Result interface{} `json:"result"`
+	}{Result: result}
+	err := jsonRequest(method, p.APIURL+pth, map[string]string{"Authorization": "Bearer " + p.APIToken}, body, &resp)
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		msgs := make([]string, 0)
+		for _, e := range resp.Errors {
+			msgs = append(msgs, fmt.Sprintf("%d: %s", e.Code, e.Message))
+		}
+		return fmt.Errorf("Cloudflare API request failed: %s", strings.Join(msgs, ", "))
+	}
+	return nil
+}
+
+// findZone returns the id of the most specific zone the name belongs to
+func (p *CloudflareProvider) findZone(name string) (string, error) {
+	for _, candidate := range zoneCandidates(name) {
+		zones := make([]cloudflareZone, 0)
+		err := p.request("GET", "/zones?name="+url.QueryEscape(strings.TrimSuffix(candidate, ".")), nil, &zones)
+		if err != nil {
+			return "", err
+		}
+		if len(zones) > 0 {
+			return zones[0].ID, nil
+		}
+	}
+	return "", fmt.Errorf("Could not find a Cloudflare zone for %s", name)
+}
+
+func (p *CloudflareProvider) listRecords(zoneID string, name string, rtype string) ([]cloudflareDNSRecord, error) {
+	cfrecs := make([]cloudflareDNSRecord, 0)
+	query := url.Values{}
+	query.Set("name", strings.TrimSuffix(dns.Fqdn(name), "."))
+	query.Set("type", rtype)
+	query.Set("per_page", "100")
+	err := p.request("GET", "/zones/"+url.PathEscape(zoneID)+"/dns_records?"+query.Encode(), nil, &cfrecs)
+	return cfrecs, err
+}
+
+func (p *CloudflareProvider) ListRecords(name string, rtype string) ([]records.Record, error) {
+	recs := make([]records.Record, 0)
+	zoneID, err := p.findZone(name)
+	if err != nil {
+		return recs, err
+	}
+	cfrecs, err := p.listRecords(zoneID, name, rtype)
+	if err != nil {
+		return recs, err
+	}
+	for _, cf := range cfrecs {
+		rdata := cf.Content
+		if cf.Data != nil && cf.Type == "CAA" {
+			rdata = fmt.Sprintf("%d %s \"%s\"", cf.Data.Flags, cf.Data.Tag, cf.Data.Value)
+		} else if cf.Type == "CNAME" {
+			rdata = dns.Fqdn(rdata)
+		}
+		rec, err := records.Parse(cf.Name, cf.Type, cf.TTL, rdata)
+		if err != nil {
+			return recs, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+func (p *CloudflareProvider) CreateOrReplace(recs []records.Record) error {
+	for _, set := range records.Group(recs) {
+		zoneID, err := p.findZone(set.Name)
+		if err != nil {
+			return err
+		}
+		err = p.deleteRecords(zoneID, set.Name, set.Type)
+		if err != nil {
+			return err
+		}
+		for _, r := range set.Records {
+			cf := cloudflareDNSRecord{Type: r.Type, Name: strings.TrimSuffix(r.Name, "."), TTL: r.TTL}
+			if r.Type == "CAA" {
+				cf.Data = &cloudflareData{Flags: r.Flag, Tag: r.Tag, Value: r.Value}
+			} else {
+				cf.Content = strings.TrimSuffix(r.RData(), ".")
+			}
+			err = p.request("POST", "/zones/"+url.PathEscape(zoneID)+"/dns_records", cf, nil)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *CloudflareProvider) Delete(name string, rtype string) error {
+	zoneID, err := p.findZone(name)
+	if err != nil {
+		return err
+	}
+	return p.deleteRecords(zoneID, name, rtype)
+}
+
+func (p *CloudflareProvider) deleteRecords(zoneID string, name string, rtype string) error {
+	cfrecs, err := p.listRecords(zoneID, name, rtype)
+	if err != nil {
+		return err
+	}
+	for _, cf := range cfrecs {
+		err = p.request("DELETE", "/zones/"+url.PathEscape(zoneID)+"/dns_records/"+url.PathEscape(cf.ID), nil, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package dnsprovider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/acme-dns/acme-dns-client/pkg/records"
+)
+
+// cloudflareStandIn is a minimal Cloudflare API v4 serving a single zone
+type cloudflareStandIn struct {
+	mu      sync.Mutex
+	records []cloudflareDNSRecord
+	nextID  int
+	// failPost makes the record creation fail with an API error
+	failPost bool
+}
+
+func (s *cloudflareStandIn) respond(w http.ResponseWriter, result interface{}) {
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "errors": []string{}, "result": result})
+}
+
+func (s *cloudflareStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"success": false, "errors": [{"code": 9109, "message": "Invalid access token"}]}`))
+		return
+	}
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/zones":
+		zones := []cloudflareZone{}
+		if r.URL.Query().Get("name") == "example.org" {
+			zones = append(zones, cloudflareZone{ID: "zone1", Name: "example.org"})
+		}
+		s.respond(w, zones)
+	case r.Method == "GET" && r.URL.Path == "/zones/zone1/dns_records":
+		found := []cloudflareDNSRecord{}
+		for _, rec := range s.records {
+			if rec.Name == r.URL.Query().Get("name") && rec.Type == r.URL.Query().Get("type") {
+				found = append(found, rec)
+			}
+		}
+		s.respond(w, found)
+	case r.Method == "POST" && r.URL.Path == "/zones/zone1/dns_records":
+		if s.failPost {
+			w.Write([]byte(`{"success": false, "errors": [{"code": 81053, "message": "An A, AAAA, or CNAME record with that host already exists."}]}`))
+			return
+		}
+		rec := cloudflareDNSRecord{}
+		json.NewDecoder(r.Body).Decode(&rec)
+		s.nextID++
+		rec.ID = fmt.Sprintf("rec%d", s.nextID)
+		s.records = append(s.records, rec)
+		s.respond(w, rec)
+	case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/zones/zone1/dns_records/"):
+		id := strings.TrimPrefix(r.URL.Path, "/zones/zone1/dns_records/")
+		kept := []cloudflareDNSRecord{}
+		for _, rec := range s.records {
+			if rec.ID != id {
+				kept = append(kept, rec)
+			}
+		}
+		s.records = kept
+		s.respond(w, map[string]string{"id": id})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newCloudflareTestProvider(t *testing.T, standIn *cloudflareStandIn, token string) DNSProvider {
+	srv := httptest.NewServer(standIn)
+	t.Cleanup(srv.Close)
+	p, err := New("cloudflare", Credentials{"CLOUDFLARE_API_URL": srv.URL, "CLOUDFLARE_API_TOKEN": token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestCloudflareCreate(t *testing.T) {
+	standIn := &cloudflareStandIn{}
+	p := newCloudflareTestProvider(t, standIn, "secret")
+	recs := append([]records.Record{records.ChallengeCNAME("example.org", "abc.auth.example.net")},
+		records.CAAPair("example.org", "letsencrypt.org")...)
+	if err := p.CreateOrReplace(recs); err != nil {
+		t.Fatalf("CreateOrReplace failed: %s", err)
+	}
+	cname, err := p.ListRecords("_acme-challenge.example.org", "CNAME")
+	if err != nil {
+		t.Fatalf("ListRecords failed: %s", err)
+	}
+	if len(cname) != 1 || cname[0].Value != "abc.auth.example.net." {
+		t.Errorf("Expected the created CNAME record, got %v", cname)
+	}
+	caa, err := p.ListRecords("example.org", "CAA")
+	if err != nil {
+		t.Fatalf("ListRecords failed: %s", err)
+	}
+	if len(caa) != 2 || caa[0].Tag != "issue" || caa[1].Tag != "issuewild" || caa[0].Value != "letsencrypt.org" {
+		t.Errorf("Expected the created CAA records, got %v", caa)
+	}
+}
+
+func TestCloudflareReplace(t *testing.T) {
+	standIn := &cloudflareStandIn{records: []cloudflareDNSRecord{
+		{ID: "old1", Type: "CNAME", Name: "_acme-challenge.example.org", Content: "old.auth.example.net", TTL: 300},
+		{ID: "other", Type: "CNAME", Name: "www.example.org", Content: "example.org", TTL: 300},
+	}}
+	p := newCloudflareTestProvider(t, standIn, "secret")
+	err := p.CreateOrReplace([]records.Record{records.ChallengeCNAME("example.org", "abc.auth.example.net")})
+	if err != nil {
+		t.Fatalf("CreateOrReplace failed: %s", err)
+	}
+	if len(standIn.records) != 2 {
+		t.Fatalf("Expected the old CNAME record to be replaced, got %v", standIn.records)
+	}
+	for _, rec := range standIn.records {
+		if rec.ID == "old1" {
+			t.Errorf("Expected the old CNAME record to be deleted")
+		}
+		if rec.Name == "_acme-challenge.example.org" && rec.Content != "abc.auth.example.net" {
+			t.Errorf("Unexpected CNAME target %s", rec.Content)
+		}
+	}
+	if err = p.Delete("_acme-challenge.example.org", "CNAME"); err != nil {
+		t.Fatalf("Delete failed: %s", err)
+	}
+	if len(standIn.records) != 1 || standIn.records[0].ID != "other" {
+		t.Errorf("Expected only the unrelated record to remain, got %v", standIn.records)
+	}
+}
+
+func TestCloudflareErrors(t *testing.T) {
+	p := newCloudflareTestProvider(t, &cloudflareStandIn{failPost: true}, "secret")
+	err := p.CreateOrReplace([]records.Record{records.ChallengeCNAME("example.org", "abc.auth.example.net")})
+	if err == nil || !strings.Contains(err.Error(), "81053") {
+		t.Errorf("Expected the API error to be returned, got: %v", err)
+	}
+	_, err = p.ListRecords("_acme-challenge.example.com", "CNAME")
+	if err == nil || !strings.Contains(err.Error(), "Could not find a Cloudflare zone") {
+		t.Errorf("Expected a missing zone error, got: %v", err)
+	}
+	p = newCloudflareTestProvider(t, &cloudflareStandIn{}, "wrong")
+	_, err = p.ListRecords("_acme-challenge.example.org", "CNAME")
+	if err == nil || !strings.Contains(err.Error(), "status 403") {
+		t.Errorf("Expected an authorization error, got: %v", err)
+	}
+}
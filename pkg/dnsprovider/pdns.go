@@ -0,0 +1,129 @@
+package dnsprovider
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/acme-dns/acme-dns-client/pkg/records"
+
+	"github.com/miekg/dns"
+)
+
+// PowerDNSProvider manages records using the PowerDNS Authoritative Server HTTP API.
+// Configuration: PDNS_API_URL, PDNS_API_KEY and optionally PDNS_SERVER_ID (default: localhost)
+type PowerDNSProvider struct {
+	APIURL   string
+	APIKey   string
+	ServerID string
+}
+
+type pdnsZone struct {
+	ID     string      `json:"id,omitempty"`
+	Name   string      `json:"name,omitempty"`
+	RRsets []pdnsRRset `json:"rrsets,omitempty"`
+}
+
+type pdnsRRset struct {
+	Name       string       `json:"name"`
+	Type       string       `json:"type"`
+	TTL        int          `json:"ttl,omitempty"`
+	ChangeType string       `json:"changetype,omitempty"`
+	Records    []pdnsRecord `json:"records"`
+}
+
+type pdnsRecord struct {
+	Content  string `json:"content"`
+	Disabled bool   `json:"disabled"`
+}
+
+// NewPowerDNSProvider returns a new PowerDNSProvider instance
+func NewPowerDNSProvider(creds Credentials) (DNSProvider, error) {
+	if err := creds.require("PDNS_API_URL", "PDNS_API_KEY"); err != nil {
+		return nil, err
+	}
+	return &PowerDNSProvider{
+		APIURL:   strings.TrimSuffix(creds.Get("PDNS_API_URL"), "/"),
+		APIKey:   creds.Get("PDNS_API_KEY"),
+		ServerID: creds.GetDefault("PDNS_SERVER_ID", "localhost"),
+	}, nil
+}
+
+func (p *PowerDNSProvider) Name() string {
+	return "PowerDNS"
+}
+
+func (p *PowerDNSProvider) request(method string, pth string, body interface{}, out interface{}) error {
+	return jsonRequest(method, p.APIURL+"/api/v1/servers/"+url.PathEscape(p.ServerID)+pth,
+		map[string]string{"X-API-Key": p.APIKey}, body, out)
+}
+
+// findZone returns the id of the most specific zone the name belongs to
+func (p *PowerDNSProvider) findZone(name string) (string, error) {
+	zones := make([]pdnsZone, 0)
+	err := p.request("GET", "/zones", nil, &zones)
+	if err != nil {
+		return "", err
+	}
+	for _, candidate := range zoneCandidates(name) {
+		for _, z := range zones {
+			if strings.EqualFold(dns.Fqdn(z.Name), candidate) {
+				return z.ID, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("Could not find a PowerDNS zone for %s", name)
+}
+
+func (p *PowerDNSProvider) ListRecords(name string, rtype string) ([]records.Record, error) {
+	recs := make([]records.Record, 0)
+	zoneID, err := p.findZone(name)
+	if err != nil {
+		return recs, err
+	}
+	zone := pdnsZone{}
+	err = p.request("GET", "/zones/"+url.PathEscape(zoneID), nil, &zone)
+	if err != nil {
+		return recs, err
+	}
+	for _, set := range zone.RRsets {
+		if !strings.EqualFold(dns.Fqdn(set.Name), dns.Fqdn(name)) || set.Type != rtype {
+			continue
+		}
+		for _, r := range set.Records {
+			rec, err := records.Parse(set.Name, set.Type, set.TTL, r.Content)
+			if err != nil {
+				return recs, err
+			}
+			recs = append(recs, rec)
+		}
+	}
+	return recs, nil
+}
+
+func (p *PowerDNSProvider) CreateOrReplace(recs []records.Record) error {
+	for _, set := range records.Group(recs) {
+		zoneID, err := p.findZone(set.Name)
+		if err != nil {
+			return err
+		}
+		rrset := pdnsRRset{Name: set.Name, Type: set.Type, TTL: set.TTL, ChangeType: "REPLACE"}
+		for _, r := range set.Records {
+			rrset.Records = append(rrset.Records, pdnsRecord{Content: r.RData()})
+		}
+		err = p.request("PATCH", "/zones/"+url.PathEscape(zoneID), pdnsZone{RRsets: []pdnsRRset{rrset}}, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *PowerDNSProvider) Delete(name string, rtype string) error {
+	zoneID, err := p.findZone(name)
+	if err != nil {
+		return err
+	}
+	rrset := pdnsRRset{Name: dns.Fqdn(name), Type: rtype, ChangeType: "DELETE", Records: []pdnsRecord{}}
+	return p.request("PATCH", "/zones/"+url.PathEscape(zoneID), pdnsZone{RRsets: []pdnsRRset{rrset}}, nil)
+}
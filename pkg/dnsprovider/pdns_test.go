@@ -0,0 +1,128 @@
+package dnsprovider
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/acme-dns/acme-dns-client/pkg/records"
+)
+
+// pdnsStandIn is a minimal PowerDNS API serving a single zone
+type pdnsStandIn struct {
+	mu     sync.Mutex
+	rrsets []pdnsRRset
+	// fail makes the PATCH requests fail with an API error
+	fail bool
+}
+
+func (s *pdnsStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Header.Get("X-API-Key") != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/api/v1/servers/localhost/zones":
+		json.NewEncoder(w).Encode([]pdnsZone{{ID: "example.org.", Name: "example.org."}})
+	case r.Method == "GET" && r.URL.Path == "/api/v1/servers/localhost/zones/example.org.":
+		json.NewEncoder(w).Encode(pdnsZone{ID: "example.org.", Name: "example.org.", RRsets: s.rrsets})
+	case r.Method == "PATCH" && r.URL.Path == "/api/v1/servers/localhost/zones/example.org.":
+		if s.fail {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"error": "RRset _acme-challenge.example.org. IN CNAME: Conflicts with pre-existing RRset"}`))
+			return
+		}
+		zone := pdnsZone{}
+		json.NewDecoder(r.Body).Decode(&zone)
+		for _, change := range zone.RRsets {
+			kept := make([]pdnsRRset, 0)
+			for _, set := range s.rrsets {
+				if set.Name != change.Name || set.Type != change.Type {
+					kept = append(kept, set)
+				}
+			}
+			if change.ChangeType == "REPLACE" {
+				change.ChangeType = ""
+				kept = append(kept, change)
+			}
+			s.rrsets = kept
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newPowerDNSTestProvider(t *testing.T, standIn *pdnsStandIn) DNSProvider {
+	srv := httptest.NewServer(standIn)
+	t.Cleanup(srv.Close)
+	p, err := New("pdns", Credentials{"PDNS_API_URL": srv.URL + "/", "PDNS_API_KEY": "secret", "PDNS_SERVER_ID": "localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestPowerDNSCreate(t *testing.T) {
+	standIn := &pdnsStandIn{}
+	p := newPowerDNSTestProvider(t, standIn)
+	err := p.CreateOrReplace([]records.Record{records.ChallengeCNAME("example.org", "abc.auth.example.net")})
+	if err != nil {
+		t.Fatalf("CreateOrReplace failed: %s", err)
+	}
+	recs, err := p.ListRecords("_acme-challenge.example.org", "CNAME")
+	if err != nil {
+		t.Fatalf("ListRecords failed: %s", err)
+	}
+	if len(recs) != 1 || recs[0].Value != "abc.auth.example.net." {
+		t.Errorf("Expected the created CNAME record, got %v", recs)
+	}
+}
+
+func TestPowerDNSReplace(t *testing.T) {
+	standIn := &pdnsStandIn{rrsets: []pdnsRRset{
+		{Name: "example.org.", Type: "CAA", TTL: 300, Records: []pdnsRecord{{Content: `0 issue "ca.example.net"`}}},
+	}}
+	p := newPowerDNSTestProvider(t, standIn)
+	err := p.CreateOrReplace(records.CAAPair("example.org", "letsencrypt.org")[:1])
+	if err != nil {
+		t.Fatalf("CreateOrReplace failed: %s", err)
+	}
+	recs, err := p.ListRecords("example.org", "CAA")
+	if err != nil {
+		t.Fatalf("ListRecords failed: %s", err)
+	}
+	if len(recs) != 1 || recs[0].Value != "letsencrypt.org" {
+		t.Errorf("Expected the CAA record to be replaced, got %v", recs)
+	}
+	// Add keeps the existing records
+	err = Add(p, []records.Record{records.NewCAA("example.org", "issue", "ca.example.net")})
+	if err != nil {
+		t.Fatalf("Add failed: %s", err)
+	}
+	recs, _ = p.ListRecords("example.org", "CAA")
+	if len(recs) != 2 {
+		t.Errorf("Expected both CAA records after Add, got %v", recs)
+	}
+}
+
+func TestPowerDNSErrors(t *testing.T) {
+	p := newPowerDNSTestProvider(t, &pdnsStandIn{fail: true})
+	err := p.CreateOrReplace([]records.Record{records.ChallengeCNAME("example.org", "abc.auth.example.net")})
+	if err == nil || !strings.Contains(err.Error(), "status 422") || !strings.Contains(err.Error(), "Conflicts") {
+		t.Errorf("Expected the API error to be returned, got: %v", err)
+	}
+	_, err = p.ListRecords("_acme-challenge.example.com", "CNAME")
+	if err == nil || !strings.Contains(err.Error(), "Could not find a PowerDNS zone") {
+		t.Errorf("Expected a missing zone error, got: %v", err)
+	}
+	p, _ = New("pdns", Credentials{"PDNS_API_URL": "http://127.0.0.1:1", "PDNS_API_KEY": "wrong"})
+	if _, err = p.ListRecords("example.org", "CAA"); err == nil {
+		t.Error("Expected a connection error")
+	}
+}
@@ -0,0 +1,204 @@
+package dnsprovider
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/acme-dns/acme-dns-client/pkg/records"
+
+	"github.com/miekg/dns"
+)
+
+// DNSProvider is a DNS hosting provider API that acme-dns-client can use to publish the records it needs
+type DNSProvider interface {
+	// Name returns the name of the provider
+	Name() string
+	// ListRecords returns the existing records with the given owner name and type
+	ListRecords(name string, rtype string) ([]records.Record, error)
+	// CreateOrReplace creates the records, replacing the existing records with the same owner name and type
+	CreateOrReplace(recs []records.Record) error
+	// Delete removes all the records with the given owner name and type
+	Delete(name string, rtype string) error
+}
+
+var (
+	providers = map[string]func(Credentials) (DNSProvider, error){
+		"pdns":       NewPowerDNSProvider,
+		"cloudflare": NewCloudflareProvider,
+		"route53":    NewRoute53Provider,
+	}
+
+	httpClient = &http.Client{Timeout: 30 * time.Second}
+)
+
+// Credentials holds the provider configuration and credentials. Values from the configuration file take
+// precedence over the environment variables of the same name.
+type Credentials map[string]string
+
+// LoadCredentials reads the provider configuration from a JSON file containing an object with string values,
+// eg. {"PDNS_API_URL": "http://127.0.0.1:8081", "PDNS_API_KEY": "secret"}. Empty path only uses the environment.
+func LoadCredentials(pth string) (Credentials, error) {
+	creds := make(Credentials)
+	if pth == "" {
+		return creds, nil
+	}
+	data, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return creds, err
+	}
+	err = json.Unmarshal(data, &creds)
+	if err != nil {
+		return creds, fmt.Errorf("Could not parse DNS provider configuration file %s: %s", pth, err)
+	}
+	return creds, nil
+}
+
+// Get returns the configuration value for the key from the configuration file, or from the environment
+func (c Credentials) Get(key string) string {
+	if v, ok := c[key]; ok {
+		return v
+	}
+	return os.Getenv(key)
+}
+
+// GetDefault works like Get but returns defVal if the key is not set
+func (c Credentials) GetDefault(key string, defVal string) string {
+	if v := c.Get(key); v != "" {
+		return v
+	}
+	return defVal
+}
+
+// require returns an error listing the missing configuration keys
+func (c Credentials) require(keys ...string) error {
+	missing := make([]string, 0)
+	for _, k := range keys {
+		if c.Get(k) == "" {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("Missing DNS provider configuration: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Names returns the names of the supported DNS providers
+func Names() []string {
+	names := make([]string, 0)
+	for n := range providers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns a configured DNSProvider by its name
+func New(name string, creds Credentials) (DNSProvider, error) {
+	constructor, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown DNS provider: %s (supported providers: %s)", name, strings.Join(Names(), ", "))
+	}
+	return constructor(creds)
+}
+
+// Add adds the records to the existing records with the same owner name and type, leaving the existing ones intact
+func Add(p DNSProvider, recs []records.Record) error {
+	for _, set := range records.Group(recs) {
+		existing, err := p.ListRecords(set.Name, set.Type)
+		if err != nil {
+			return err
+		}
+		merged := existing
+		for _, r := range set.Records {
+			found := false
+			for _, e := range existing {
+				if e.RData() == r.RData() {
+					found = true
+				}
+			}
+			if !found {
+				merged = append(merged, r)
+			}
+		}
+		if len(merged) > len(existing) {
+			err = p.CreateOrReplace(merged)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// zoneCandidates returns the name and its parent domains, starting from the longest one
+func zoneCandidates(name string) []string {
+	labels := dns.SplitDomainName(name)
+	candidates := make([]string, 0)
+	for i := 0; i < len(labels)-1; i++ {
+		candidates = append(candidates, dns.Fqdn(strings.Join(labels[i:], ".")))
+	}
+	return candidates
+}
+
+// jsonRequest performs a HTTP request with an optional JSON request body, and unmarshals the JSON response to out
+func jsonRequest(method string, url string, headers map[string]string, body interface{}, out interface{}) error {
+	var data []byte
+	var err error
+	hdrs := map[string]string{"Accept": "application/json"}
+	if body != nil {
+		data, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		hdrs["Content-Type"] = "application/json"
+	}
+	for k, v := range headers {
+		hdrs[k] = v
+	}
+	respBody, err := doRequest(method, url, hdrs, data)
+	if err != nil {
+		return err
+	}
+	if out != nil && len(respBody) > 0 {
+		return json.Unmarshal(respBody, out)
+	}
+	return nil
+}
+
+// doRequest performs a HTTP request and returns the response body. Responses with a non-2xx status code
+// are returned as errors.
+func doRequest(method string, url string, headers map[string]string, body []byte) ([]byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return respBody, fmt.Errorf("%s %s returned status %d: %s", method, url, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return respBody, nil
+}
@@ -0,0 +1,207 @@
+package dnsprovider
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/acme-dns/acme-dns-client/pkg/records"
+
+	"github.com/miekg/dns"
+)
+
+const route53Namespace = "https://route53.amazonaws.com/doc/2013-04-01/"
+
+// Route53Provider manages records using the Amazon Route 53 API.
+// Configuration: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, optionally AWS_SESSION_TOKEN, ROUTE53_HOSTED_ZONE_ID
+// to skip the hosted zone lookup and ROUTE53_ENDPOINT.
+type Route53Provider struct {
+	Endpoint     string
+	HostedZoneID string
+	credentials  awsCredentials
+}
+
+type route53HostedZones struct {
+	HostedZones []struct {
+		ID   string `xml:"Id"`
+		Name string `xml:"Name"`
+	} `xml:"HostedZones>HostedZone"`
+}
+
+type route53RRset struct {
+	Name            string          `xml:"Name"`
+	Type            string          `xml:"Type"`
+	TTL             int             `xml:"TTL"`
+	ResourceRecords []route53Record `xml:"ResourceRecords>ResourceRecord"`
+}
+
+type route53Record struct {
+	Value string `xml:"Value"`
+}
+
+type route53RRsets struct {
+	RecordSets []route53RRset `xml:"ResourceRecordSets>ResourceRecordSet"`
+}
+
+type route53Change struct {
+	Action            string       `xml:"Action"`
+	ResourceRecordSet route53RRset `xml:"ResourceRecordSet"`
+}
+
+type route53ChangeRequest struct {
+	XMLName xml.Name        `xml:"ChangeResourceRecordSetsRequest"`
+	Xmlns   string          `xml:"xmlns,attr"`
+	Changes []route53Change `xml:"ChangeBatch>Changes>Change"`
+}
+
+// NewRoute53Provider returns a new Route53Provider instance
+func NewRoute53Provider(creds Credentials) (DNSProvider, error) {
+	if err := creds.require("AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"); err != nil {
+		return nil, err
+	}
+	return &Route53Provider{
+		Endpoint:     strings.TrimSuffix(creds.GetDefault("ROUTE53_ENDPOINT", "https://route53.amazonaws.com"), "/"),
+		HostedZoneID: creds.Get("ROUTE53_HOSTED_ZONE_ID"),
+		credentials: awsCredentials{
+			AccessKeyID:     creds.Get("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: creds.Get("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    creds.Get("AWS_SESSION_TOKEN"),
+			Region:          "us-east-1",
+			Service:         "route53",
+		},
+	}, nil
+}
+
+func (p *Route53Provider) Name() string {
+	return "Route53"
+}
+
+func (p *Route53Provider) request(method string, pth string, query url.Values, body interface{}, out interface{}) error {
+	var data []byte
+	var err error
+	headers := make(map[string]string)
+	if body != nil {
+		data, err = xml.Marshal(body)
+		if err != nil {
+			return err
+		}
+		data = append([]byte(xml.Header), data...)
+		headers["Content-Type"] = "text/xml"
+	}
+	u, err := url.Parse(p.Endpoint + "/2013-04-01" + pth)
+	if err != nil {
+		return err
+	}
+	u.RawQuery = awsCanonicalQuery(query)
+	for k, v := range p.credentials.sign(method, u, data, time.Now()) {
+		headers[k] = v
+	}
+	respBody, err := doRequest(method, u.String(), headers, data)
+	if err != nil {
+		return err
+	}
+	if out != nil {
+		return xml.Unmarshal(respBody, out)
+	}
+	return nil
+}
+
+// findZone returns the id of the most specific hosted zone the name belongs to, without the /hostedzone/ prefix
+// the API returns it with
+func (p *Route53Provider) findZone(name string) (string, error) {
+	if p.HostedZoneID != "" {
+		return strings.TrimPrefix(p.HostedZoneID, "/hostedzone/"), nil
+	}
+	for _, candidate := range zoneCandidates(name) {
+		zones := route53HostedZones{}
+		query := url.Values{}
+		query.Set("dnsname", candidate)
+		query.Set("maxitems", "1")
+		err := p.request("GET", "/hostedzonesbyname", query, nil, &zones)
+		if err != nil {
+			return "", err
+		}
+		if len(zones.HostedZones) > 0 && strings.EqualFold(dns.Fqdn(zones.HostedZones[0].Name), candidate) {
+			return strings.TrimPrefix(zones.HostedZones[0].ID, "/hostedzone/"), nil
+		}
+	}
+	return "", fmt.Errorf("Could not find a Route53 hosted zone for %s", name)
+}
+
+// listRRset returns the record set with the name and type, or nil if it does not exist
+func (p *Route53Provider) listRRset(zoneID string, name string, rtype string) (*route53RRset, error) {
+	sets := route53RRsets{}
+	query := url.Values{}
+	query.Set("name", dns.Fqdn(name))
+	query.Set("type", rtype)
+	query.Set("maxitems", "1")
+	err := p.request("GET", "/hostedzone/"+zoneID+"/rrset", query, nil, &sets)
+	if err != nil {
+		return nil, err
+	}
+	// The API returns the record sets starting from the requested name and type
+	for _, s := range sets.RecordSets {
+		if strings.EqualFold(dns.Fqdn(s.Name), dns.Fqdn(name)) && s.Type == rtype {
+			return &s, nil
+		}
+	}
+	return nil, nil
+}
+
+func (p *Route53Provider) ListRecords(name string, rtype string) ([]records.Record, error) {
+	recs := make([]records.Record, 0)
+	zoneID, err := p.findZone(name)
+	if err != nil {
+		return recs, err
+	}
+	set, err := p.listRRset(zoneID, name, rtype)
+	if err != nil || set == nil {
+		return recs, err
+	}
+	for _, rr := range set.ResourceRecords {
+		rec, err := records.Parse(set.Name, set.Type, set.TTL, rr.Value)
+		if err != nil {
+			return recs, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+func (p *Route53Provider) CreateOrReplace(recs []records.Record) error {
+	for _, set := range records.Group(recs) {
+		zoneID, err := p.findZone(set.Name)
+		if err != nil {
+			return err
+		}
+		rrset := route53RRset{Name: set.Name, Type: set.Type, TTL: set.TTL}
+		for _, r := range set.Records {
+			rrset.ResourceRecords = append(rrset.ResourceRecords, route53Record{Value: r.RData()})
+		}
+		err = p.change(zoneID, route53Change{Action: "UPSERT", ResourceRecordSet: rrset})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Route53Provider) Delete(name string, rtype string) error {
+	zoneID, err := p.findZone(name)
+	if err != nil {
+		return err
+	}
+	// Route53 requires the deleted record set to match the existing one exactly
+	set, err := p.listRRset(zoneID, name, rtype)
+	if err != nil || set == nil {
+		return err
+	}
+	return p.change(zoneID, route53Change{Action: "DELETE", ResourceRecordSet: *set})
+}
+
+func (p *Route53Provider) change(zoneID string, change route53Change) error {
+	req := route53ChangeRequest{Xmlns: route53Namespace, Changes: []route53Change{change}}
+	return p.request("POST", "/hostedzone/"+zoneID+"/rrset/", nil, req, nil)
+}
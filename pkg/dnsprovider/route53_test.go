@@ -0,0 +1,194 @@
+package dnsprovider
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/acme-dns/acme-dns-client/pkg/records"
+)
+
+// route53StandIn is a minimal Route 53 API serving a single hosted zone. The request signatures are verified
+// with the same credentials.
+type route53StandIn struct {
+	t       *testing.T
+	mu      sync.Mutex
+	rrsets  []route53RRset
+	creds   awsCredentials
+	changes int
+	// requests holds the method and path of the requests received
+	requests []string
+	// failChange makes the change requests fail with an API error
+	failChange bool
+}
+
+func (s *route53StandIn) verifySignature(r *http.Request, body []byte) bool {
+	date, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	u := *r.URL
+	u.Host = r.Host
+	return s.creds.sign(r.Method, &u, body, date)["Authorization"] == r.Header.Get("Authorization")
+}
+
+func (s *route53StandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	body, _ := ioutil.ReadAll(r.Body)
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	if !s.verifySignature(r, body) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`<ErrorResponse><Error><Code>SignatureDoesNotMatch</Code></Error></ErrorResponse>`))
+		return
+	}
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/2013-04-01/hostedzonesbyname":
+		// The API lists the hosted zones starting from the requested name
+		w.Write([]byte(`<ListHostedZonesByNameResponse><HostedZones><HostedZone><Id>/hostedzone/Z1</Id>` +
+			`<Name>example.org.</Name></HostedZone></HostedZones></ListHostedZonesByNameResponse>`))
+	case r.Method == "GET" && r.URL.Path == "/2013-04-01/hostedzone/Z1/rrset":
+		sets := route53RRsets{}
+		for _, set := range s.rrsets {
+			if set.Name == r.URL.Query().Get("name") && set.Type == r.URL.Query().Get("type") {
+				sets.RecordSets = append(sets.RecordSets, set)
+			}
+		}
+		out, _ := xml.Marshal(sets)
+		w.Write(out)
+	case r.Method == "POST" && r.URL.Path == "/2013-04-01/hostedzone/Z1/rrset/":
+		if s.failChange {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<ErrorResponse><Error><Code>InvalidChangeBatch</Code></Error></ErrorResponse>`))
+			return
+		}
+		req := route53ChangeRequest{}
+		if err := xml.Unmarshal(body, &req); err != nil {
+			s.t.Errorf("Could not parse the change request: %s", err)
+		}
+		for _, change := range req.Changes {
+			kept := make([]route53RRset, 0)
+			for _, set := range s.rrsets {
+				if set.Name != change.ResourceRecordSet.Name || set.Type != change.ResourceRecordSet.Type {
+					kept = append(kept, set)
+				}
+			}
+			if change.Action == "UPSERT" {
+				kept = append(kept, change.ResourceRecordSet)
+			}
+			s.rrsets = kept
+		}
+		s.changes++
+		w.Write([]byte(`<ChangeResourceRecordSetsResponse><ChangeInfo><Status>PENDING</Status></ChangeInfo></ChangeResourceRecordSetsResponse>`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newRoute53TestProvider(t *testing.T, standIn *route53StandIn, secret string) DNSProvider {
+	return newRoute53TestProviderForZone(t, standIn, secret, "")
+}
+
+func newRoute53TestProviderForZone(t *testing.T, standIn *route53StandIn, secret string, zoneID string) DNSProvider {
+	standIn.t = t
+	standIn.creds = awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret", Region: "us-east-1", Service: "route53"}
+	srv := httptest.NewServer(standIn)
+	t.Cleanup(srv.Close)
+	p, err := New("route53", Credentials{
+		"ROUTE53_ENDPOINT":       srv.URL,
+		"ROUTE53_HOSTED_ZONE_ID": zoneID,
+		"AWS_ACCESS_KEY_ID":      "AKIDEXAMPLE",
+		"AWS_SECRET_ACCESS_KEY":  secret,
+		"AWS_SESSION_TOKEN":      "",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestRoute53Create(t *testing.T) {
+	for _, tc := range []struct {
+		zoneID   string
+		requests []string
+	}{
+		// The zone is looked up starting from the record name
+		{"", []string{
+			"GET /2013-04-01/hostedzonesbyname",
+			"GET /2013-04-01/hostedzonesbyname",
+			"POST /2013-04-01/hostedzone/Z1/rrset/",
+			"GET /2013-04-01/hostedzonesbyname",
+			"GET /2013-04-01/hostedzonesbyname",
+			"GET /2013-04-01/hostedzone/Z1/rrset",
+		}},
+		{"Z1", []string{"POST /2013-04-01/hostedzone/Z1/rrset/", "GET /2013-04-01/hostedzone/Z1/rrset"}},
+		{"/hostedzone/Z1", []string{"POST /2013-04-01/hostedzone/Z1/rrset/", "GET /2013-04-01/hostedzone/Z1/rrset"}},
+	} {
+		standIn := &route53StandIn{}
+		p := newRoute53TestProviderForZone(t, standIn, "secret", tc.zoneID)
+		err := p.CreateOrReplace([]records.Record{records.ChallengeCNAME("example.org", "abc.auth.example.net")})
+		if err != nil {
+			t.Fatalf("%q: CreateOrReplace failed: %s", tc.zoneID, err)
+		}
+		recs, err := p.ListRecords("_acme-challenge.example.org", "CNAME")
+		if err != nil {
+			t.Fatalf("%q: ListRecords failed: %s", tc.zoneID, err)
+		}
+		if len(recs) != 1 || recs[0].Value != "abc.auth.example.net." {
+			t.Errorf("%q: expected the created CNAME record, got %v", tc.zoneID, recs)
+		}
+		if !reflect.DeepEqual(standIn.requests, tc.requests) {
+			t.Errorf("%q: expected requests %v, got %v", tc.zoneID, tc.requests, standIn.requests)
+		}
+	}
+}
+
+func TestRoute53Replace(t *testing.T) {
+	standIn := &route53StandIn{rrsets: []route53RRset{
+		{Name: "example.org.", Type: "CAA", TTL: 300, ResourceRecords: []route53Record{{Value: `0 issue "ca.example.net"`}}},
+	}}
+	p := newRoute53TestProvider(t, standIn, "secret")
+	err := p.CreateOrReplace(records.CAAPair("example.org", "letsencrypt.org"))
+	if err != nil {
+		t.Fatalf("CreateOrReplace failed: %s", err)
+	}
+	recs, err := p.ListRecords("example.org", "CAA")
+	if err != nil {
+		t.Fatalf("ListRecords failed: %s", err)
+	}
+	if len(recs) != 2 || recs[0].Value != "letsencrypt.org" {
+		t.Errorf("Expected the CAA record set to be replaced, got %v", recs)
+	}
+	if standIn.changes != 1 {
+		t.Errorf("Expected a single UPSERT change for the record set, got %d", standIn.changes)
+	}
+	if err = p.Delete("example.org", "CAA"); err != nil {
+		t.Fatalf("Delete failed: %s", err)
+	}
+	if len(standIn.rrsets) != 0 {
+		t.Errorf("Expected the record set to be deleted, got %v", standIn.rrsets)
+	}
+}
+
+func TestRoute53Errors(t *testing.T) {
+	p := newRoute53TestProvider(t, &route53StandIn{failChange: true}, "secret")
+	err := p.CreateOrReplace([]records.Record{records.ChallengeCNAME("example.org", "abc.auth.example.net")})
+	if err == nil || !strings.Contains(err.Error(), "InvalidChangeBatch") {
+		t.Errorf("Expected the API error to be returned, got: %v", err)
+	}
+	_, err = p.ListRecords("_acme-challenge.example.com", "CNAME")
+	if err == nil || !strings.Contains(err.Error(), "Could not find a Route53 hosted zone") {
+		t.Errorf("Expected a missing zone error, got: %v", err)
+	}
+	p = newRoute53TestProvider(t, &route53StandIn{}, "wrong")
+	_, err = p.ListRecords("_acme-challenge.example.org", "CNAME")
+	if err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Errorf("Expected a signature error, got: %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package dnsprovider
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// awsCredentials holds the credentials for signing AWS API requests with Signature Version 4
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Region          string
+	Service         string
+}
+
+// sign returns the headers required to authenticate the request
+func (c *awsCredentials) sign(method string, u *url.URL, body []byte, now time.Time) map[string]string {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	headers := map[string]string{
+		"host":       u.Host,
+		"x-amz-date": amzDate,
+	}
+	if c.SessionToken != "" {
+		headers["x-amz-security-token"] = c.SessionToken
+	}
+
+	names := make([]string, 0)
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", k, strings.TrimSpace(headers[k]))
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	pth := u.EscapedPath()
+	if pth == "" {
+		pth = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		method,
+		pth,
+		awsCanonicalQuery(u.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+
+	scope := strings.Join([]string{date, c.Region, c.Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretAccessKey), date)
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, c.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	delete(headers, "host")
+	headers["Authorization"] = fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.AccessKeyID, scope, signedHeaders, signature)
+	return headers
+}
+
+// awsCanonicalQuery returns the query string sorted and encoded as required by Signature Version 4
+func awsCanonicalQuery(query url.Values) string {
+	keys := make([]string, 0)
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	params := make([]string, 0)
+	for _, k := range keys {
+		values := query[k]
+		sort.Strings(values)
+		for _, v := range values {
+			params = append(params, awsEscape(k)+"="+awsEscape(v))
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+func awsEscape(input string) string {
+	return strings.Replace(url.QueryEscape(input), "+", "%20", -1)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,55 @@
+package dnsprovider
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The test vectors are from the AWS Signature Version 4 test suite
+func TestSigV4KnownAnswers(t *testing.T) {
+	creds := awsCredentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:          "us-east-1",
+		Service:         "service",
+	}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		url       string
+		signature string
+	}{
+		{"get-vanilla", "https://example.amazonaws.com/", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-query-order-key-case", "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			"b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+	}
+	for _, tc := range tests {
+		u, err := url.Parse(tc.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		headers := creds.sign("GET", u, nil, now)
+		want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+			"SignedHeaders=host;x-amz-date, Signature=" + tc.signature
+		if headers["Authorization"] != want {
+			t.Errorf("%s: expected Authorization %q, got %q", tc.name, want, headers["Authorization"])
+		}
+		if headers["x-amz-date"] != "20150830T123600Z" {
+			t.Errorf("%s: unexpected x-amz-date %q", tc.name, headers["x-amz-date"])
+		}
+	}
+}
+
+func TestSigV4SessionToken(t *testing.T) {
+	creds := awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret", SessionToken: "token", Region: "us-east-1", Service: "route53"}
+	u, _ := url.Parse("https://route53.amazonaws.com/2013-04-01/hostedzonesbyname")
+	headers := creds.sign("GET", u, nil, time.Now())
+	if headers["x-amz-security-token"] != "token" {
+		t.Errorf("Expected the session token header, got %q", headers["x-amz-security-token"])
+	}
+	if !strings.Contains(headers["Authorization"], "SignedHeaders=host;x-amz-date;x-amz-security-token,") {
+		t.Errorf("Expected the session token to be signed, got %q", headers["Authorization"])
+	}
+}
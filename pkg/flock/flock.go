@@ -0,0 +1,36 @@
+package flock
+
+import (
+	"os"
+)
+
+// Lock is an exclusive advisory lock on a file, shared between acme-dns-client processes
+type Lock struct {
+	path string
+	file *os.File
+}
+
+// Acquire blocks until an exclusive lock for the path is acquired. The lock file is created if needed.
+func Acquire(path string) (*Lock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	l := &Lock{path: path, file: f}
+	err = l.lock()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Release releases the lock
+func (l *Lock) Release() error {
+	err := l.unlock()
+	cerr := l.file.Close()
+	if err != nil {
+		return err
+	}
+	return cerr
+}
@@ -0,0 +1,18 @@
+// +build !windows
+
+package flock
+
+import "syscall"
+
+func (l *Lock) lock() error {
+	for {
+		err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+func (l *Lock) unlock() error {
+	return syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+}
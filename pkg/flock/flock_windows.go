@@ -0,0 +1,37 @@
+// +build windows
+
+package flock
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// The lock is taken with LockFileEx on the first byte of the lock file. The lock is held by the file handle, so
+// Windows releases it when the process exits, even after a crash.
+
+const LOCKFILE_EXCLUSIVE_LOCK = 0x2
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+func (l *Lock) lock() error {
+	ol := new(syscall.Overlapped)
+	r, _, err := procLockFileEx.Call(l.file.Fd(), LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, uintptr(unsafe.Pointer(ol)))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
+
+func (l *Lock) unlock() error {
+	ol := new(syscall.Overlapped)
+	r, _, err := procUnlockFileEx.Call(l.file.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(ol)))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
@@ -1,8 +1,11 @@
 package integration
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
 	"net/url"
+	"os"
 	"strings"
 )
 
@@ -14,6 +17,15 @@ type ACMEAccount struct {
 }
 
 func (c *ACMEAccount) CAARecordString() (string, error) {
+	value, err := c.CAAValue()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("\"%s\"", value), nil
+}
+
+// CAAValue returns the unquoted CAA property value limiting the issuance to this ACME account
+func (c *ACMEAccount) CAAValue() (string, error) {
 	// Get issuer from URI
 	acctURL, err := url.Parse(c.URI)
 	if err != nil {
@@ -22,14 +34,67 @@ func (c *ACMEAccount) CAARecordString() (string, error) {
 	hostparts := strings.Split(acctURL.Host, ".")
 	if len(hostparts) > 1 {
 		cadomain := strings.Join(hostparts[len(hostparts)-2:], ".")
-		return fmt.Sprintf("\"%s; validationmethods=dns-01; accounturi=%s\"", cadomain, c.URI), nil
+		return fmt.Sprintf("%s; validationmethods=dns-01; accounturi=%s", cadomain, c.URI), nil
 	}
 	return "", fmt.Errorf("Encountered an error while trying to determine issuer domain from account URI host: %s", acctURL.Host)
 }
 
 func GetIntegrations() []ACMEClient {
-	// Only Certbot supported right now
 	integrations := make([]ACMEClient, 0)
+	integrations = append(integrations, NewGenericClient())
 	integrations = append(integrations, NewCertbotClient())
+	integrations = append(integrations, NewAcmeshClient())
+	integrations = append(integrations, NewLegoClient())
+	integrations = append(integrations, NewDehydratedClient())
+	integrations = append(integrations, NewUacmeClient())
+	integrations = append(integrations, NewGetsslClient())
+	integrations = append(integrations, NewTraefikClient())
+	integrations = append(integrations, NewCaddyClient())
 	return integrations
-}
\ No newline at end of file
+}
+
+// uniqueStrings returns the input without duplicate values, preserving the order
+func uniqueStrings(input []string) []string {
+	seen := make(map[string]bool)
+	out := make([]string, 0)
+	for _, s := range input {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// keyAuthorizationDigest returns the dns-01 TXT record value for a key authorization
+func keyAuthorizationDigest(keyAuth string) string {
+	digest := sha256.Sum256([]byte(keyAuth))
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// existingPaths returns the paths that exist on the system
+func existingPaths(paths []string) []string {
+	existing := make([]string, 0)
+	for _, p := range paths {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			existing = append(existing, p)
+		}
+	}
+	return existing
+}
+
+// readOnlyIntegration implements the validation methods of ACMEClient for ACME clients that handle the
+// validation by themselves, and are only used for account discovery
+type readOnlyIntegration struct{}
+
+func (r readOnlyIntegration) FindValidationToken() (string, error) {
+	return "", fmt.Errorf("Validation hooks are not supported for this ACME client")
+}
+
+func (r readOnlyIntegration) FindValidationDomain() (string, error) {
+	return "", fmt.Errorf("Validation hooks are not supported for this ACME client")
+}
+
+func (r readOnlyIntegration) HookEvent() HookEvent {
+	return HookNone
+}
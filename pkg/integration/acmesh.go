@@ -0,0 +1,125 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type AcmeshAccount struct {
+	Contact []string `json:"contact"`
+	Status  string   `json:"status"`
+}
+
+// AcmeshClient reads the ACME accounts from acme.sh configuration. acme.sh uses its own DNS API scripts
+// instead of external hooks, so the integration is only used for CAA record guidance.
+type AcmeshClient struct {
+	readOnlyIntegration
+	ConfigRoots []string
+}
+
+func (c *AcmeshClient) String() string {
+	return fmt.Sprintf("acme.sh (%s)", strings.Join(c.ConfigRoots, ", "))
+}
+
+func (c *AcmeshClient) Name() string {
+	return "acme.sh"
+}
+
+// NewAcmeshClient returns a new AcmeshClient instance. The configuration is looked up from $LE_CONFIG_HOME,
+// and ~/.acme.sh of both the current user and root.
+func NewAcmeshClient() *AcmeshClient {
+	roots := make([]string, 0)
+	if confHome := os.Getenv("LE_CONFIG_HOME"); confHome != "" {
+		roots = append(roots, confHome)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		roots = append(roots, filepath.Join(home, ".acme.sh"))
+	}
+	roots = append(roots, "/root/.acme.sh")
+	return &AcmeshClient{ConfigRoots: uniqueStrings(roots)}
+}
+
+// Found checks if acme.sh configuration directory is found on the system
+func (c *AcmeshClient) Found() bool {
+	return len(c.existingRoots()) > 0
+}
+
+func (c *AcmeshClient) existingRoots() []string {
+	roots := make([]string, 0)
+	for _, r := range c.ConfigRoots {
+		if _, err := os.Stat(r); !os.IsNotExist(err) {
+			roots = append(roots, r)
+		}
+	}
+	return roots
+}
+
+// FindAccounts searches through the acme.sh CA directories for ACME accounts. The account URI is read from
+// ca.conf and the contact from account.json residing in the same directory. Account files that cannot be parsed,
+// eg. ones still being written by acme.sh, are skipped and reported in the returned error along with the rest
+// of the accounts.
+func (c *AcmeshClient) FindAccounts() ([]ACMEAccount, error) {
+	var accounts = make([]ACMEAccount, 0)
+	skipped := make([]string, 0)
+	for _, root := range c.existingRoots() {
+		caDir := filepath.Join(root, "ca")
+		if _, err := os.Stat(caDir); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.Walk(caDir,
+			func(pth string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if !info.IsDir() && info.Name() == "account.json" {
+					newAcc, err := c.ParseAccountFile(pth)
+					if err != nil {
+						skipped = append(skipped, err.Error())
+						return nil
+					}
+					accounts = append(accounts, newAcc)
+				}
+				return nil
+			})
+		if err != nil {
+			return accounts, err
+		}
+	}
+	if len(skipped) > 0 {
+		return accounts, fmt.Errorf("Skipped acme.sh account files: %s", strings.Join(skipped, "; "))
+	}
+	return accounts, nil
+}
+
+// ParseAccountFile parses acme.sh account.json and the ca.conf next to it to a ACMEAccount struct
+func (c *AcmeshClient) ParseAccountFile(pth string) (ACMEAccount, error) {
+	acmeacc := ACMEAccount{
+		FilePath: pth,
+		Client:   c.Name(),
+	}
+	data, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return acmeacc, err
+	}
+	shacc := AcmeshAccount{}
+	err = json.Unmarshal(data, &shacc)
+	if err != nil {
+		return acmeacc, err
+	}
+	if len(shacc.Contact) > 0 {
+		acmeacc.Contact = shacc.Contact[0]
+	}
+	caconf, err := parseShellConfig(filepath.Join(filepath.Dir(pth), "ca.conf"))
+	if err != nil {
+		return acmeacc, err
+	}
+	acmeacc.URI = caconf["ACCOUNT_URL"]
+	if acmeacc.URI == "" {
+		return acmeacc, fmt.Errorf("No ACCOUNT_URL found in acme.sh configuration for %s", pth)
+	}
+	return acmeacc, nil
+}
@@ -0,0 +1,41 @@
+package integration
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAcmeshSkipsBrokenAccounts(t *testing.T) {
+	root, err := ioutil.TempDir("", "acmedns-acmesh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	for _, tc := range []struct {
+		ca     string
+		caconf string
+	}{
+		{"acme-v02.api.letsencrypt.org/directory", "ACCOUNT_URL='https://acme-v02.api.letsencrypt.org/acme/acct/1'\n"},
+		// A ca.conf still being written by acme.sh
+		{"acme.zerossl.com/v2/DV90", "CA_EAB_KEY_ID='abc'\n"},
+	} {
+		dir := filepath.Join(root, "ca", tc.ca)
+		if err = os.MkdirAll(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		ioutil.WriteFile(filepath.Join(dir, "account.json"), []byte(`{"contact": ["mailto:user@example.org"]}`), 0600)
+		ioutil.WriteFile(filepath.Join(dir, "ca.conf"), []byte(tc.caconf), 0600)
+	}
+	c := &AcmeshClient{ConfigRoots: []string{root}}
+	accounts, err := c.FindAccounts()
+	if err == nil || !strings.Contains(err.Error(), "DV90") {
+		t.Errorf("Expected the broken account file to be reported, got: %v", err)
+	}
+	if len(accounts) != 1 || accounts[0].URI != "https://acme-v02.api.letsencrypt.org/acme/acct/1" ||
+		accounts[0].Contact != "mailto:user@example.org" {
+		t.Errorf("Expected the working account to be found, got %v", accounts)
+	}
+}
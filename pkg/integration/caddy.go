@@ -0,0 +1,114 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CaddyAccount covers both the current CertMagic account format and the older lego based one
+type CaddyAccount struct {
+	Contact      []string `json:"contact"`
+	Location     string   `json:"location"`
+	Email        string   `json:"Email"`
+	Registration struct {
+		URI string `json:"uri"`
+	} `json:"Registration"`
+}
+
+// CaddyClient reads the ACME accounts from Caddy (CertMagic) storage. Caddy handles the validation by itself,
+// so the integration is only used for CAA record guidance.
+type CaddyClient struct {
+	readOnlyIntegration
+	StorageRoots []string
+}
+
+func (c *CaddyClient) String() string {
+	return fmt.Sprintf("Caddy (%s)", strings.Join(c.StorageRoots, ", "))
+}
+
+func (c *CaddyClient) Name() string {
+	return "Caddy"
+}
+
+// NewCaddyClient returns a new CaddyClient instance. The storage directories can be defined with a comma
+// separated list in $ACMEDNS_CADDY_STORAGE, otherwise the default data directories are used.
+func NewCaddyClient() *CaddyClient {
+	roots := make([]string, 0)
+	if storage := os.Getenv("ACMEDNS_CADDY_STORAGE"); storage != "" {
+		return &CaddyClient{StorageRoots: strings.Split(storage, ",")}
+	}
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		roots = append(roots, filepath.Join(dataHome, "caddy"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		roots = append(roots, filepath.Join(home, ".local", "share", "caddy"))
+	}
+	roots = append(roots, "/var/lib/caddy/.local/share/caddy", "/root/.local/share/caddy")
+	return &CaddyClient{StorageRoots: uniqueStrings(roots)}
+}
+
+// Found checks if any of the Caddy storage directories exist
+func (c *CaddyClient) Found() bool {
+	return len(existingPaths(c.StorageRoots)) > 0
+}
+
+// FindAccounts searches for accounts stored as acme/<ca>/users/<email>/<local part of email>.json by CertMagic,
+// or acme/<ca>/users/<email>/<email>.json by Caddy v1. Accounts without an email are stored in users/default.
+func (c *CaddyClient) FindAccounts() ([]ACMEAccount, error) {
+	var accounts = make([]ACMEAccount, 0)
+	for _, r := range existingPaths(c.StorageRoots) {
+		matches, err := filepath.Glob(filepath.Join(r, "acme", "*", "users", "*", "*.json"))
+		if err != nil {
+			return accounts, err
+		}
+		for _, pth := range matches {
+			if !isCaddyAccountFile(pth) {
+				continue
+			}
+			newAcc, err := c.ParseAccountFile(pth)
+			if err != nil {
+				return accounts, err
+			}
+			accounts = append(accounts, newAcc)
+		}
+	}
+	return accounts, nil
+}
+
+// isCaddyAccountFile returns true if the file in a user directory is the account registration file, the
+// directory also holds the private key
+func isCaddyAccountFile(pth string) bool {
+	user := filepath.Base(filepath.Dir(pth))
+	name := strings.TrimSuffix(filepath.Base(pth), ".json")
+	return name == user || name == strings.SplitN(user, "@", 2)[0]
+}
+
+// ParseAccountFile parses Caddy account file to a ACMEAccount struct
+func (c *CaddyClient) ParseAccountFile(pth string) (ACMEAccount, error) {
+	acmeacc := ACMEAccount{
+		FilePath: pth,
+		Client:   c.Name(),
+	}
+	data, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return acmeacc, err
+	}
+	caddyacc := CaddyAccount{}
+	err = json.Unmarshal(data, &caddyacc)
+	if err != nil {
+		return acmeacc, err
+	}
+	acmeacc.Contact = caddyacc.Email
+	if len(caddyacc.Contact) > 0 {
+		acmeacc.Contact = caddyacc.Contact[0]
+	}
+	acmeacc.URI = caddyacc.Location
+	if acmeacc.URI == "" {
+		acmeacc.URI = caddyacc.Registration.URI
+	}
+	return acmeacc, nil
+}
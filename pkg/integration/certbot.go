@@ -2,6 +2,7 @@ package integration
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
@@ -96,11 +97,31 @@ func (c *CertbotClient) ParseAccountFile(pth string) (ACMEAccount, error) {
 // FindValidationToken attempts to find a ACME validation token. For Certbot this is distributed
 // via environmental variable CERTBOT_VALIDATION
 func (c *CertbotClient) FindValidationToken() (string, error) {
-	return os.Getenv("CERTBOT_VALIDATION"), nil
+	token := os.Getenv("CERTBOT_VALIDATION")
+	if token == "" {
+		return "", fmt.Errorf("Environment variable CERTBOT_VALIDATION is not set")
+	}
+	return token, nil
 }
 
 // FindValidationDomain attempts to find the domain the ACME validation is going to be carried for.
 // For Certbot this is distributed via environmental variable CERTBOT_DOMAIN
 func (c *CertbotClient) FindValidationDomain() (string, error) {
-	return os.Getenv("CERTBOT_DOMAIN"), nil
+	domain := os.Getenv("CERTBOT_DOMAIN")
+	if domain == "" {
+		return "", fmt.Errorf("Environment variable CERTBOT_DOMAIN is not set")
+	}
+	return domain, nil
+}
+
+// HookEvent determines the hook event from the environment. Certbot sets CERTBOT_AUTH_OUTPUT only for the
+// cleanup hook.
+func (c *CertbotClient) HookEvent() HookEvent {
+	if os.Getenv("CERTBOT_VALIDATION") == "" {
+		return HookNone
+	}
+	if _, ok := os.LookupEnv("CERTBOT_AUTH_OUTPUT"); ok {
+		return HookCleanup
+	}
+	return HookDeploy
 }
\ No newline at end of file
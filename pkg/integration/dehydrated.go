@@ -0,0 +1,166 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var dehydratedHooks = []string{
+	"deploy_challenge", "clean_challenge", "sync_cert", "deploy_cert", "deploy_ocsp", "unchanged_cert",
+	"invalid_challenge", "request_failure", "generate_csr", "startup_hook", "exit_hook",
+	"this_hookscript_is_broken__dehydrated_is_working_fine__please_ignore_unknown_hooks_in_your_script",
+}
+
+type DehydratedAccount struct {
+	Contact []string `json:"contact"`
+	Status  string   `json:"status"`
+}
+
+type DehydratedAccountID struct {
+	URL string `json:"url"`
+}
+
+// DehydratedClient supports account discovery from dehydrated configuration directory and using
+// acme-dns-client directly as the dehydrated hook script, including HOOK_CHAIN=yes.
+type DehydratedClient struct {
+	ConfigRoots []string
+	// Args are the command line arguments acme-dns-client was called with, without the program name
+	Args []string
+}
+
+func (c *DehydratedClient) String() string {
+	return fmt.Sprintf("dehydrated (%s)", strings.Join(c.ConfigRoots, ", "))
+}
+
+func (c *DehydratedClient) Name() string {
+	return "dehydrated"
+}
+
+// NewDehydratedClient returns a new DehydratedClient instance
+func NewDehydratedClient() *DehydratedClient {
+	roots := make([]string, 0)
+	if basedir := os.Getenv("BASEDIR"); basedir != "" {
+		roots = append(roots, basedir)
+	}
+	roots = append(roots, "/etc/dehydrated", "/usr/local/etc/dehydrated", "/var/lib/dehydrated")
+	return &DehydratedClient{ConfigRoots: uniqueStrings(roots), Args: os.Args[1:]}
+}
+
+// Found checks if dehydrated account directory is found on the system
+func (c *DehydratedClient) Found() bool {
+	for _, r := range c.ConfigRoots {
+		if _, err := os.Stat(filepath.Join(r, "accounts")); !os.IsNotExist(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindAccounts searches for dehydrated accounts stored as accounts/<ca>/registration_info.json
+func (c *DehydratedClient) FindAccounts() ([]ACMEAccount, error) {
+	var accounts = make([]ACMEAccount, 0)
+	for _, r := range c.ConfigRoots {
+		matches, err := filepath.Glob(filepath.Join(r, "accounts", "*", "registration_info.json"))
+		if err != nil {
+			return accounts, err
+		}
+		for _, pth := range matches {
+			newAcc, err := c.ParseAccountFile(pth)
+			if err != nil {
+				return accounts, err
+			}
+			accounts = append(accounts, newAcc)
+		}
+	}
+	return accounts, nil
+}
+
+// ParseAccountFile parses dehydrated registration_info.json, and the account URL from account_id.json next to it
+func (c *DehydratedClient) ParseAccountFile(pth string) (ACMEAccount, error) {
+	acmeacc := ACMEAccount{
+		FilePath: pth,
+		Client:   c.Name(),
+	}
+	data, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return acmeacc, err
+	}
+	dhacc := DehydratedAccount{}
+	err = json.Unmarshal(data, &dhacc)
+	if err != nil {
+		return acmeacc, err
+	}
+	if len(dhacc.Contact) > 0 {
+		acmeacc.Contact = dhacc.Contact[0]
+	}
+	data, err = ioutil.ReadFile(filepath.Join(filepath.Dir(pth), "account_id.json"))
+	if err != nil {
+		// Older dehydrated versions do not store the account URL
+		return acmeacc, nil
+	}
+	accountID := DehydratedAccountID{}
+	err = json.Unmarshal(data, &accountID)
+	acmeacc.URI = accountID.URL
+	return acmeacc, err
+}
+
+// HookEvent returns the dehydrated hook event from the first argument
+func (c *DehydratedClient) HookEvent() HookEvent {
+	if len(c.Args) < 1 {
+		return HookNone
+	}
+	switch c.Args[0] {
+	case "deploy_challenge":
+		return HookDeploy
+	case "clean_challenge":
+		return HookCleanup
+	}
+	for _, h := range dehydratedHooks {
+		if c.Args[0] == h {
+			return HookIgnored
+		}
+	}
+	return HookNone
+}
+
+// FindValidations parses the "deploy_challenge <domain> <token_file> <token_value> [...]" arguments. With
+// HOOK_CHAIN=yes the arguments are repeated for each domain.
+func (c *DehydratedClient) FindValidations() ([]ValidationRequest, error) {
+	reqs := make([]ValidationRequest, 0)
+	if len(c.Args) < 1 || (c.Args[0] != "deploy_challenge" && c.Args[0] != "clean_challenge") {
+		return reqs, fmt.Errorf("Not invoked as dehydrated challenge hook")
+	}
+	args := c.Args[1:]
+	if len(args) == 0 || len(args)%3 != 0 {
+		return reqs, fmt.Errorf("Unexpected dehydrated hook arguments: %s", strings.Join(c.Args, " "))
+	}
+	for i := 0; i < len(args); i += 3 {
+		reqs = append(reqs, ValidationRequest{
+			Domain: strings.TrimPrefix(args[i], "*."),
+			Token:  args[i+2],
+		})
+	}
+	return reqs, nil
+}
+
+// FindValidationToken returns the token of the first domain of the hook invocation
+func (c *DehydratedClient) FindValidationToken() (string, error) {
+	reqs, err := c.FindValidations()
+	if err != nil {
+		return "", err
+	}
+	return reqs[0].Token, nil
+}
+
+// FindValidationDomain returns the first domain of the hook invocation
+func (c *DehydratedClient) FindValidationDomain() (string, error) {
+	reqs, err := c.FindValidations()
+	if err != nil {
+		return "", err
+	}
+	return reqs[0].Domain, nil
+}
@@ -0,0 +1,64 @@
+package integration
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// GenericClient implements a client neutral environment variable protocol for hooks: ACMEDNS_DOMAIN and
+// ACMEDNS_TOKEN define the domain and the TXT record value, and optional ACMEDNS_EVENT=cleanup marks the
+// cleanup phase.
+type GenericClient struct{}
+
+func (c *GenericClient) String() string {
+	return "Generic environment variable protocol (ACMEDNS_DOMAIN, ACMEDNS_TOKEN)"
+}
+
+func (c *GenericClient) Name() string {
+	return "generic"
+}
+
+// NewGenericClient returns a new GenericClient instance
+func NewGenericClient() *GenericClient {
+	return &GenericClient{}
+}
+
+// Found always returns false, as there is no ACME client installation to look for
+func (c *GenericClient) Found() bool {
+	return false
+}
+
+// FindAccounts returns no accounts, as there is no ACME client installation to look for
+func (c *GenericClient) FindAccounts() ([]ACMEAccount, error) {
+	return make([]ACMEAccount, 0), nil
+}
+
+// HookEvent determines the hook event from the environment variables
+func (c *GenericClient) HookEvent() HookEvent {
+	if os.Getenv("ACMEDNS_DOMAIN") == "" || os.Getenv("ACMEDNS_TOKEN") == "" {
+		return HookNone
+	}
+	if strings.ToLower(os.Getenv("ACMEDNS_EVENT")) == "cleanup" {
+		return HookCleanup
+	}
+	return HookDeploy
+}
+
+// FindValidationToken returns the token from ACMEDNS_TOKEN environment variable
+func (c *GenericClient) FindValidationToken() (string, error) {
+	token := os.Getenv("ACMEDNS_TOKEN")
+	if token == "" {
+		return "", fmt.Errorf("Environment variable ACMEDNS_TOKEN is not set")
+	}
+	return token, nil
+}
+
+// FindValidationDomain returns the domain from ACMEDNS_DOMAIN environment variable
+func (c *GenericClient) FindValidationDomain() (string, error) {
+	domain := os.Getenv("ACMEDNS_DOMAIN")
+	if domain == "" {
+		return "", fmt.Errorf("Environment variable ACMEDNS_DOMAIN is not set")
+	}
+	return strings.TrimPrefix(domain, "*."), nil
+}
@@ -0,0 +1,112 @@
+package integration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GetsslClient supports using acme-dns-client as getssl DNS_ADD_COMMAND and DNS_DEL_COMMAND, configured as
+// "acme-dns-client getssl add" and "acme-dns-client getssl del" respectively.
+type GetsslClient struct {
+	ConfigRoots []string
+	// Args are the command line arguments acme-dns-client was called with, without the program name
+	Args []string
+}
+
+func (c *GetsslClient) String() string {
+	return fmt.Sprintf("getssl (%s)", strings.Join(c.ConfigRoots, ", "))
+}
+
+func (c *GetsslClient) Name() string {
+	return "getssl"
+}
+
+// NewGetsslClient returns a new GetsslClient instance. The working directory is looked up from
+// $WORKING_DIR and ~/.getssl of both the current user and root.
+func NewGetsslClient() *GetsslClient {
+	roots := make([]string, 0)
+	if wd := os.Getenv("WORKING_DIR"); wd != "" {
+		roots = append(roots, wd)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		roots = append(roots, filepath.Join(home, ".getssl"))
+	}
+	roots = append(roots, "/root/.getssl")
+	return &GetsslClient{ConfigRoots: uniqueStrings(roots), Args: os.Args[1:]}
+}
+
+// Found checks if getssl configuration is found on the system
+func (c *GetsslClient) Found() bool {
+	return len(c.configFiles()) > 0
+}
+
+func (c *GetsslClient) configFiles() []string {
+	files := make([]string, 0)
+	for _, r := range c.ConfigRoots {
+		pth := filepath.Join(r, "getssl.cfg")
+		if _, err := os.Stat(pth); !os.IsNotExist(err) {
+			files = append(files, pth)
+		}
+	}
+	return files
+}
+
+// FindAccounts reads the account metadata from getssl.cfg. getssl does not store the account URI, so only the
+// contact address is available.
+func (c *GetsslClient) FindAccounts() ([]ACMEAccount, error) {
+	var accounts = make([]ACMEAccount, 0)
+	for _, pth := range c.configFiles() {
+		conf, err := parseShellConfig(pth)
+		if err != nil {
+			return accounts, err
+		}
+		if conf["ACCOUNT_EMAIL"] == "" && conf["ACCOUNT_KEY"] == "" {
+			continue
+		}
+		accounts = append(accounts, ACMEAccount{
+			Contact:  conf["ACCOUNT_EMAIL"],
+			FilePath: pth,
+			Client:   c.Name(),
+		})
+	}
+	return accounts, nil
+}
+
+// HookEvent determines the hook event from "getssl add|del <domain> <token>" arguments
+func (c *GetsslClient) HookEvent() HookEvent {
+	if _, err := c.hookArgs(); err != nil {
+		return HookNone
+	}
+	if c.Args[1] == "del" {
+		return HookCleanup
+	}
+	return HookDeploy
+}
+
+// FindValidationToken returns the TXT record value argument
+func (c *GetsslClient) FindValidationToken() (string, error) {
+	args, err := c.hookArgs()
+	if err != nil {
+		return "", err
+	}
+	return args[1], nil
+}
+
+// FindValidationDomain returns the domain argument
+func (c *GetsslClient) FindValidationDomain() (string, error) {
+	args, err := c.hookArgs()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(args[0], "*."), nil
+}
+
+// hookArgs returns the domain and token arguments
+func (c *GetsslClient) hookArgs() ([]string, error) {
+	if len(c.Args) != 4 || c.Args[0] != "getssl" || (c.Args[1] != "add" && c.Args[1] != "del") {
+		return nil, fmt.Errorf("Not invoked as getssl DNS command")
+	}
+	return c.Args[2:], nil
+}
@@ -1,5 +1,31 @@
 package integration
 
+// HookEvent is the challenge lifecycle event an ACME client invoked acme-dns-client for
+type HookEvent int
+
+const (
+	// HookNone means that acme-dns-client was not invoked as a hook by the ACME client
+	HookNone HookEvent = iota
+	// HookDeploy means that the validation token should be published
+	HookDeploy
+	// HookCleanup means that the challenge has been completed, successfully or not
+	HookCleanup
+	// HookIgnored is an event of the ACME client that does not require any action
+	HookIgnored
+)
+
+// ValidationRequest is a TXT record update for a domain requested by the ACME client
+type ValidationRequest struct {
+	Domain string
+	Token  string
+}
+
+// BatchValidator is implemented by ACME client integrations that can request TXT record updates for several
+// domains in a single hook invocation
+type BatchValidator interface {
+	FindValidations() ([]ValidationRequest, error)
+}
+
 type ACMEClient interface {
 	FindAccounts() ([]ACMEAccount, error)
 	Found()	bool
@@ -7,4 +33,5 @@ type ACMEClient interface {
 	Name() string
 	FindValidationToken() (string, error)
 	FindValidationDomain() (string, error)
+	HookEvent() HookEvent
 }
@@ -0,0 +1,166 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type LegoAccount struct {
+	Email        string `json:"email"`
+	Registration struct {
+		Body struct {
+			Status  string   `json:"status"`
+			Contact []string `json:"contact"`
+		} `json:"body"`
+		URI string `json:"uri"`
+	} `json:"registration"`
+}
+
+// LegoClient supports account discovery from lego storage and using acme-dns-client as the EXEC_PATH
+// program of lego exec DNS provider.
+type LegoClient struct {
+	ConfigRoots []string
+	// Args are the command line arguments acme-dns-client was called with, without the program name
+	Args []string
+}
+
+func (c *LegoClient) String() string {
+	return fmt.Sprintf("lego (%s)", strings.Join(c.ConfigRoots, ", "))
+}
+
+func (c *LegoClient) Name() string {
+	return "lego"
+}
+
+// NewLegoClient returns a new LegoClient instance. Accounts are looked up from $LEGO_PATH, and .lego directory
+// in the current working directory and the home directory of the user.
+func NewLegoClient() *LegoClient {
+	roots := make([]string, 0)
+	if legoPath := os.Getenv("LEGO_PATH"); legoPath != "" {
+		roots = append(roots, legoPath)
+	}
+	if wd, err := os.Getwd(); err == nil {
+		roots = append(roots, filepath.Join(wd, ".lego"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		roots = append(roots, filepath.Join(home, ".lego"))
+	}
+	return &LegoClient{ConfigRoots: uniqueStrings(roots), Args: os.Args[1:]}
+}
+
+// Found checks if lego account storage is found on the system
+func (c *LegoClient) Found() bool {
+	for _, r := range c.ConfigRoots {
+		if _, err := os.Stat(filepath.Join(r, "accounts")); !os.IsNotExist(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindAccounts searches for lego accounts stored as accounts/<server>/<email>/account.json
+func (c *LegoClient) FindAccounts() ([]ACMEAccount, error) {
+	var accounts = make([]ACMEAccount, 0)
+	for _, r := range c.ConfigRoots {
+		matches, err := filepath.Glob(filepath.Join(r, "accounts", "*", "*", "account.json"))
+		if err != nil {
+			return accounts, err
+		}
+		for _, pth := range matches {
+			newAcc, err := c.ParseAccountFile(pth)
+			if err != nil {
+				return accounts, err
+			}
+			accounts = append(accounts, newAcc)
+		}
+	}
+	return accounts, nil
+}
+
+// ParseAccountFile parses lego account file to a ACMEAccount struct
+func (c *LegoClient) ParseAccountFile(pth string) (ACMEAccount, error) {
+	acmeacc := ACMEAccount{
+		FilePath: pth,
+		Client:   c.Name(),
+	}
+	data, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return acmeacc, err
+	}
+	legoacc := LegoAccount{}
+	err = json.Unmarshal(data, &legoacc)
+	if err != nil {
+		return acmeacc, err
+	}
+	acmeacc.Contact = legoacc.Email
+	if len(legoacc.Registration.Body.Contact) > 0 {
+		acmeacc.Contact = legoacc.Registration.Body.Contact[0]
+	}
+	acmeacc.URI = legoacc.Registration.URI
+	return acmeacc, nil
+}
+
+// HookEvent determines the hook event from lego exec provider arguments "present|cleanup <fqdn> <value>",
+// or "present|cleanup -- <domain> <token> <keyAuth>" when EXEC_MODE=RAW is used.
+func (c *LegoClient) HookEvent() HookEvent {
+	if _, err := c.execArgs(); err != nil {
+		return HookNone
+	}
+	if c.Args[0] == "cleanup" {
+		return HookCleanup
+	}
+	return HookDeploy
+}
+
+// FindValidationToken returns the TXT record value from the lego exec provider arguments. In raw mode the
+// value is computed from the key authorization.
+func (c *LegoClient) FindValidationToken() (string, error) {
+	args, err := c.execArgs()
+	if err != nil {
+		return "", err
+	}
+	if c.rawMode() {
+		return keyAuthorizationDigest(args[2]), nil
+	}
+	return args[1], nil
+}
+
+// FindValidationDomain returns the domain from the lego exec provider arguments. In the default mode lego passes
+// the FQDN after following the _acme-challenge CNAME record, so it is the acme-dns account domain once the CNAME
+// record is set up. The client maps it back to the registered domain.
+func (c *LegoClient) FindValidationDomain() (string, error) {
+	args, err := c.execArgs()
+	if err != nil {
+		return "", err
+	}
+	domain := strings.TrimSuffix(args[0], ".")
+	domain = strings.TrimPrefix(domain, "_acme-challenge.")
+	return strings.TrimPrefix(domain, "*."), nil
+}
+
+// rawMode returns true if lego exec provider is configured with EXEC_MODE=RAW
+func (c *LegoClient) rawMode() bool {
+	return len(c.Args) > 1 && c.Args[1] == "--" || strings.ToUpper(os.Getenv("EXEC_MODE")) == "RAW"
+}
+
+// execArgs returns the lego exec provider arguments following the command
+func (c *LegoClient) execArgs() ([]string, error) {
+	if len(c.Args) < 1 || (c.Args[0] != "present" && c.Args[0] != "cleanup") {
+		return nil, fmt.Errorf("Not invoked by lego exec provider")
+	}
+	args := c.Args[1:]
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if c.rawMode() && len(args) == 3 {
+		return args, nil
+	}
+	if !c.rawMode() && len(args) == 2 {
+		return args, nil
+	}
+	return nil, fmt.Errorf("Unexpected lego exec provider arguments: %s", strings.Join(c.Args, " "))
+}
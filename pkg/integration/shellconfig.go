@@ -0,0 +1,36 @@
+package integration
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// parseShellConfig parses a file of shell variable assignments, like the configuration files written by
+// shell script based ACME clients. Quotes around the values are removed.
+func parseShellConfig(pth string) (map[string]string, error) {
+	values := make(map[string]string)
+	f, err := os.Open(pth)
+	if err != nil {
+		return values, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		fields := strings.SplitN(line, "=", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(fields[1])
+		if len(value) > 1 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(fields[0])] = value
+	}
+	return values, scanner.Err()
+}
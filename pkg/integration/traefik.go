@@ -0,0 +1,107 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type TraefikAccount struct {
+	Email        string `json:"Email"`
+	Registration struct {
+		Body struct {
+			Status  string   `json:"status"`
+			Contact []string `json:"contact"`
+		} `json:"body"`
+		URI string `json:"uri"`
+	} `json:"Registration"`
+}
+
+type TraefikResolver struct {
+	Account *TraefikAccount `json:"Account"`
+}
+
+// TraefikClient reads the ACME accounts from Traefik acme.json storage files. Traefik handles the validation
+// by itself, so the integration is only used for CAA record guidance.
+type TraefikClient struct {
+	readOnlyIntegration
+	StorageFiles []string
+}
+
+func (c *TraefikClient) String() string {
+	return fmt.Sprintf("Traefik (%s)", strings.Join(c.StorageFiles, ", "))
+}
+
+func (c *TraefikClient) Name() string {
+	return "Traefik"
+}
+
+// NewTraefikClient returns a new TraefikClient instance. The storage files can be defined with a comma
+// separated list in $ACMEDNS_TRAEFIK_STORAGE, otherwise the common locations are used.
+func NewTraefikClient() *TraefikClient {
+	files := []string{"/etc/traefik/acme.json", "/etc/traefik/acme/acme.json", "/letsencrypt/acme.json"}
+	if storage := os.Getenv("ACMEDNS_TRAEFIK_STORAGE"); storage != "" {
+		files = strings.Split(storage, ",")
+	}
+	return &TraefikClient{StorageFiles: files}
+}
+
+// Found checks if any of the Traefik storage files exist
+func (c *TraefikClient) Found() bool {
+	return len(existingPaths(c.StorageFiles)) > 0
+}
+
+// FindAccounts returns the accounts of all the certificate resolvers in the storage files
+func (c *TraefikClient) FindAccounts() ([]ACMEAccount, error) {
+	var accounts = make([]ACMEAccount, 0)
+	for _, pth := range existingPaths(c.StorageFiles) {
+		accts, err := c.ParseStorageFile(pth)
+		if err != nil {
+			return accounts, err
+		}
+		accounts = append(accounts, accts...)
+	}
+	return accounts, nil
+}
+
+// ParseStorageFile parses Traefik acme.json. Traefik v2 keys the data by the certificate resolver name,
+// while Traefik v1 stores a single account at the top level.
+func (c *TraefikClient) ParseStorageFile(pth string) ([]ACMEAccount, error) {
+	var accounts = make([]ACMEAccount, 0)
+	data, err := ioutil.ReadFile(pth)
+	if err != nil {
+		return accounts, err
+	}
+	resolvers := make(map[string]TraefikResolver)
+	if v1 := (TraefikResolver{}); json.Unmarshal(data, &v1) == nil && v1.Account != nil {
+		resolvers["default"] = v1
+	} else if err = json.Unmarshal(data, &resolvers); err != nil {
+		return accounts, err
+	}
+	names := make([]string, 0)
+	for name := range resolvers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		r := resolvers[name]
+		if r.Account == nil || r.Account.Registration.URI == "" {
+			continue
+		}
+		acmeacc := ACMEAccount{
+			URI:      r.Account.Registration.URI,
+			Contact:  r.Account.Email,
+			FilePath: filepath.Clean(pth) + "#" + name,
+			Client:   c.Name(),
+		}
+		if len(r.Account.Registration.Body.Contact) > 0 {
+			acmeacc.Contact = r.Account.Registration.Body.Contact[0]
+		}
+		accounts = append(accounts, acmeacc)
+	}
+	return accounts, nil
+}
@@ -0,0 +1,102 @@
+package integration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UacmeClient supports using acme-dns-client directly as the uacme hook program
+type UacmeClient struct {
+	ConfigRoots []string
+	// Args are the command line arguments acme-dns-client was called with, without the program name
+	Args []string
+}
+
+func (c *UacmeClient) String() string {
+	return fmt.Sprintf("uacme (%s)", strings.Join(c.ConfigRoots, ", "))
+}
+
+func (c *UacmeClient) Name() string {
+	return "uacme"
+}
+
+// NewUacmeClient returns a new UacmeClient instance. The configuration directory is looked up from
+// $UACME_CONFDIR and the default locations.
+func NewUacmeClient() *UacmeClient {
+	roots := make([]string, 0)
+	if confdir := os.Getenv("UACME_CONFDIR"); confdir != "" {
+		roots = append(roots, confdir)
+	}
+	roots = append(roots, "/etc/ssl/uacme", "/usr/local/etc/ssl/uacme")
+	return &UacmeClient{ConfigRoots: uniqueStrings(roots), Args: os.Args[1:]}
+}
+
+// Found checks if uacme account key is found on the system
+func (c *UacmeClient) Found() bool {
+	return len(c.accountKeys()) > 0
+}
+
+func (c *UacmeClient) accountKeys() []string {
+	keys := make([]string, 0)
+	for _, r := range c.ConfigRoots {
+		pth := filepath.Join(r, "private", "key.pem")
+		if _, err := os.Stat(pth); !os.IsNotExist(err) {
+			keys = append(keys, pth)
+		}
+	}
+	return keys
+}
+
+// FindAccounts returns the uacme account keys found in <confdir>/private/key.pem. uacme does not store the
+// account URI, it is looked up from the CA using the key on each run, so it has to be filled in manually.
+func (c *UacmeClient) FindAccounts() ([]ACMEAccount, error) {
+	var accounts = make([]ACMEAccount, 0)
+	for _, pth := range c.accountKeys() {
+		accounts = append(accounts, ACMEAccount{FilePath: pth, Client: c.Name()})
+	}
+	return accounts, nil
+}
+
+// HookEvent determines the hook event from uacme hook arguments "begin|done|failed <type> <ident> <token> <auth>"
+func (c *UacmeClient) HookEvent() HookEvent {
+	if _, err := c.hookArgs(); err != nil {
+		return HookNone
+	}
+	if c.Args[0] == "begin" {
+		return HookDeploy
+	}
+	return HookCleanup
+}
+
+// FindValidationToken returns the auth argument of the uacme hook. For dns-01 uacme passes the digest of the
+// key authorization, which is the TXT record value as-is.
+func (c *UacmeClient) FindValidationToken() (string, error) {
+	args, err := c.hookArgs()
+	if err != nil {
+		return "", err
+	}
+	return args[3], nil
+}
+
+// FindValidationDomain returns the identifier argument of the uacme hook
+func (c *UacmeClient) FindValidationDomain() (string, error) {
+	args, err := c.hookArgs()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(args[1], "*."), nil
+}
+
+// hookArgs returns the uacme hook arguments following the method. Only dns-01 challenges are accepted, so
+// that uacme falls back to the next challenge type for the others.
+func (c *UacmeClient) hookArgs() ([]string, error) {
+	if len(c.Args) != 5 || (c.Args[0] != "begin" && c.Args[0] != "done" && c.Args[0] != "failed") {
+		return nil, fmt.Errorf("Not invoked as uacme hook")
+	}
+	if c.Args[1] != "dns-01" {
+		return nil, fmt.Errorf("Unsupported uacme challenge type: %s", c.Args[1])
+	}
+	return c.Args[1:], nil
+}
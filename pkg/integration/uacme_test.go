@@ -0,0 +1,34 @@
+package integration
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUacmeHookArgs(t *testing.T) {
+	auth := "LPJNul-wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ"
+	for _, tc := range []struct {
+		args   string
+		event  HookEvent
+		domain string
+		token  string
+	}{
+		{"begin dns-01 example.org tok " + auth, HookDeploy, "example.org", auth},
+		{"begin dns-01 *.example.org tok " + auth, HookDeploy, "example.org", auth},
+		{"done dns-01 example.org tok " + auth, HookCleanup, "example.org", auth},
+		{"failed dns-01 example.org tok " + auth, HookCleanup, "example.org", auth},
+		{"begin http-01 example.org tok " + auth, HookNone, "", ""},
+		{"begin dns-01 example.org tok", HookNone, "", ""},
+		{"issue example.org", HookNone, "", ""},
+	} {
+		c := &UacmeClient{Args: strings.Fields(tc.args)}
+		if event := c.HookEvent(); event != tc.event {
+			t.Errorf("%q: expected hook event %d, got %d", tc.args, tc.event, event)
+		}
+		domain, _ := c.FindValidationDomain()
+		token, _ := c.FindValidationToken()
+		if domain != tc.domain || token != tc.token {
+			t.Errorf("%q: expected %q %q, got %q %q", tc.args, tc.domain, tc.token, domain, token)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// +build !windows
+
+package logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const journaldSocket = "/run/systemd/journal/socket"
+
+// journaldLogger sends the messages to journald using its native protocol, so that the fields are stored as
+// journal fields and can be queried, eg. with journalctl ACMEDNS_DOMAIN=example.org
+type journaldLogger struct {
+	conn       *net.UnixConn
+	identifier string
+}
+
+func newJournald(identifier string) (Logger, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journaldSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &journaldLogger{conn: conn, identifier: identifier}, nil
+}
+
+// journaldPriority maps the level to the syslog priority used by journald
+func journaldPriority(level Level) int {
+	switch level {
+	case LevelDebug:
+		return 7
+	case LevelWarning:
+		return 4
+	case LevelError:
+		return 3
+	}
+	return 6
+}
+
+func (l *journaldLogger) Log(level Level, msg string, fields Fields) error {
+	buf := &bytes.Buffer{}
+	writeJournalField(buf, "MESSAGE", msg)
+	writeJournalField(buf, "PRIORITY", strconv.Itoa(journaldPriority(level)))
+	writeJournalField(buf, "SYSLOG_IDENTIFIER", l.identifier)
+	for k, v := range fields {
+		writeJournalField(buf, journalFieldName(k), v)
+	}
+	_, err := l.conn.Write(buf.Bytes())
+	return err
+}
+
+func (l *journaldLogger) Close() error {
+	return l.conn.Close()
+}
+
+// journalFieldName converts the field name to a valid journal field name in the ACMEDNS_ namespace
+func journalFieldName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		}
+		return '_'
+	}, name)
+	return "ACMEDNS_" + name
+}
+
+// writeJournalField writes the field in the journald native protocol format. Values containing newlines are
+// written with an explicit length.
+func writeJournalField(buf *bytes.Buffer, name string, value string) {
+	buf.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteByte('\n')
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, uint64(len(value)))
+	buf.Write(size)
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
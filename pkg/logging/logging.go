@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Level is the severity of a log message
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarning
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarning:
+		return "warning"
+	case LevelError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// Structured field names used by acme-dns-client
+const (
+	FIELD_DOMAIN      = "domain"
+	FIELD_ACCOUNT     = "account"
+	FIELD_SERVER      = "server"
+	FIELD_INTEGRATION = "integration"
+	FIELD_ERROR_CLASS = "error_class"
+)
+
+// Fields are the structured fields attached to a log message
+type Fields map[string]string
+
+// Logger is a logging backend
+type Logger interface {
+	Log(level Level, msg string, fields Fields) error
+	Close() error
+}
+
+// Backends lists the supported logging backend names. "file:<path>" is accepted in addition to these.
+var Backends = []string{"auto", "none", "stderr", "syslog", "journald", "file:<path>"}
+
+// New returns the logging backend for the spec: one of Backends. The identifier is used to tag the messages
+// in syslog and journald. "auto" logs to journald or syslog when there is no terminal, and nowhere otherwise,
+// as the console output is then read directly.
+func New(spec string, identifier string) (Logger, error) {
+	switch {
+	case spec == "" || spec == "auto":
+		if IsTerminal(os.Stdout) {
+			return Discard, nil
+		}
+		if l, err := newJournald(identifier); err == nil {
+			return l, nil
+		}
+		if l, err := newSyslog(identifier); err == nil {
+			return l, nil
+		}
+		return Discard, nil
+	case spec == "none":
+		return Discard, nil
+	case spec == "stderr":
+		return &writerLogger{w: os.Stderr}, nil
+	case spec == "syslog":
+		return newSyslog(identifier)
+	case spec == "journald":
+		return newJournald(identifier)
+	case strings.HasPrefix(spec, "file:"):
+		return newFile(strings.TrimPrefix(spec, "file:"))
+	}
+	return nil, fmt.Errorf("Unknown logging backend %q, supported backends: %s", spec, strings.Join(Backends, ", "))
+}
+
+// IsTerminal returns true if the file is a terminal
+func IsTerminal(f *os.File) bool {
+	st, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return st.Mode()&os.ModeCharDevice != 0
+}
+
+type discardLogger struct{}
+
+func (discardLogger) Log(Level, string, Fields) error { return nil }
+func (discardLogger) Close() error                    { return nil }
+
+// Discard is a Logger that drops all messages
+var Discard Logger = discardLogger{}
+
+// formatFields formats the fields as sorted key=value pairs, quoting the values when needed
+func formatFields(fields Fields) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v := fields[k]
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			v = strconv.Quote(v)
+		}
+		parts = append(parts, k+"="+v)
+	}
+	return strings.Join(parts, " ")
+}
+
+// withFields appends the formatted fields to the message
+func withFields(msg string, fields Fields) string {
+	if len(fields) == 0 {
+		return msg
+	}
+	return msg + " " + formatFields(fields)
+}
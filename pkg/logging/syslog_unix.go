@@ -0,0 +1,37 @@
+// +build !windows
+
+package logging
+
+import (
+	"log/syslog"
+)
+
+// syslogLogger sends the messages to the local syslog daemon, with the fields appended to the message
+type syslogLogger struct {
+	w *syslog.Writer
+}
+
+func newSyslog(identifier string) (Logger, error) {
+	w, err := syslog.New(syslog.LOG_DAEMON|syslog.LOG_INFO, identifier)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogLogger{w: w}, nil
+}
+
+func (l *syslogLogger) Log(level Level, msg string, fields Fields) error {
+	msg = withFields(msg, fields)
+	switch level {
+	case LevelDebug:
+		return l.w.Debug(msg)
+	case LevelWarning:
+		return l.w.Warning(msg)
+	case LevelError:
+		return l.w.Err(msg)
+	}
+	return l.w.Info(msg)
+}
+
+func (l *syslogLogger) Close() error {
+	return l.w.Close()
+}
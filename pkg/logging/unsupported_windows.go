@@ -0,0 +1,13 @@
+package logging
+
+import (
+	"fmt"
+)
+
+func newSyslog(identifier string) (Logger, error) {
+	return nil, fmt.Errorf("syslog logging is not supported on Windows")
+}
+
+func newJournald(identifier string) (Logger, error) {
+	return nil, fmt.Errorf("journald logging is not supported on Windows")
+}
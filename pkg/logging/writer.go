@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// writerLogger writes the messages as text lines, eg. to stderr
+type writerLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *writerLogger) Log(level Level, msg string, fields Fields) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err := fmt.Fprintf(l.w, "%s %s %s\n", time.Now().Format(time.RFC3339), level, withFields(msg, fields))
+	return err
+}
+
+func (l *writerLogger) Close() error {
+	return nil
+}
+
+// fileLogger appends the messages to a file as JSON lines
+type fileLogger struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func newFile(path string) (Logger, error) {
+	if path == "" {
+		return nil, fmt.Errorf("Log file path is missing, use file:<path>")
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("Could not open log file: %s", err)
+	}
+	return &fileLogger{f: f}, nil
+}
+
+func (l *fileLogger) Log(level Level, msg string, fields Fields) error {
+	record := map[string]string{}
+	for k, v := range fields {
+		record[k] = v
+	}
+	record["time"] = time.Now().Format(time.RFC3339)
+	record["level"] = level.String()
+	record["message"] = msg
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.f.Write(append(data, '\n'))
+	return err
+}
+
+func (l *fileLogger) Close() error {
+	return l.f.Close()
+}
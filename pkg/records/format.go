@@ -0,0 +1,223 @@
+package records
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Formats lists the supported output formats for DNS record snippets
+	Formats = []string{"bind", "tinydns", "terraform", "octodns", "dnscontrol", "cloudflare-json", "route53-changebatch"}
+
+	formatters = map[string]func(string, []Record) (string, error){
+		"bind":                formatBind,
+		"tinydns":             formatTinydns,
+		"terraform":           formatTerraform,
+		"octodns":             formatOctodns,
+		"dnscontrol":          formatDnscontrol,
+		"cloudflare-json":     formatCloudflare,
+		"route53-changebatch": formatRoute53,
+	}
+
+	nonIdentifier = regexp.MustCompile("[^a-z0-9]+")
+)
+
+// ValidFormat returns true if the output format is supported
+func ValidFormat(format string) bool {
+	_, ok := formatters[format]
+	return ok
+}
+
+// Format returns the records formatted for the given output format. Zone is used for formats that use
+// record names relative to the DNS zone.
+func Format(format string, zone string, recs []Record) (string, error) {
+	formatter, ok := formatters[format]
+	if !ok {
+		return "", fmt.Errorf("Unknown record format: %s (supported formats: %s)", format, strings.Join(Formats, ", "))
+	}
+	return formatter(zone, recs)
+}
+
+func formatBind(zone string, recs []Record) (string, error) {
+	var out strings.Builder
+	for _, r := range recs {
+		fmt.Fprintf(&out, "%s\t%d\tIN\t%s\t%s\n", r.Name, r.TTL, r.Type, r.RData())
+	}
+	return out.String(), nil
+}
+
+func formatTinydns(zone string, recs []Record) (string, error) {
+	var out strings.Builder
+	for _, r := range recs {
+		name := strings.TrimSuffix(r.Name, ".")
+		switch r.Type {
+		case "CNAME":
+			fmt.Fprintf(&out, "C%s:%s:%d\n", name, strings.TrimSuffix(r.Value, "."), r.TTL)
+		case "CAA":
+			// tinydns-data does not know CAA, so it has to be added as a generic record with octal escaped rdata
+			rdata := []byte{r.Flag, byte(len(r.Tag))}
+			rdata = append(rdata, []byte(r.Tag)...)
+			rdata = append(rdata, []byte(r.Value)...)
+			fmt.Fprintf(&out, ":%s:257:%s:%d\n", name, tinydnsEscape(rdata), r.TTL)
+		default:
+			return "", fmt.Errorf("Unsupported record type for tinydns format: %s", r.Type)
+		}
+	}
+	return out.String(), nil
+}
+
+func tinydnsEscape(data []byte) string {
+	var out strings.Builder
+	for _, b := range data {
+		if b < 0x21 || b > 0x7e || b == ':' || b == '\\' {
+			fmt.Fprintf(&out, "\\%03o", b)
+		} else {
+			out.WriteByte(b)
+		}
+	}
+	return out.String()
+}
+
+func formatTerraform(zone string, recs []Record) (string, error) {
+	var out strings.Builder
+	for _, set := range Group(recs) {
+		values := make([]string, 0)
+		for _, r := range set.Records {
+			values = append(values, hclQuote(r.RData()))
+		}
+		resName := nonIdentifier.ReplaceAllString(strings.ToLower(strings.TrimSuffix(set.Name, ".")+"_"+set.Type), "_")
+		fmt.Fprintf(&out, "resource \"aws_route53_record\" \"%s\" {\n", strings.Trim(resName, "_"))
+		fmt.Fprintf(&out, "  zone_id = var.zone_id\n")
+		fmt.Fprintf(&out, "  name    = %s\n", hclQuote(set.Name))
+		fmt.Fprintf(&out, "  type    = %s\n", hclQuote(set.Type))
+		fmt.Fprintf(&out, "  ttl     = %d\n", set.TTL)
+		fmt.Fprintf(&out, "  records = [%s]\n", strings.Join(values, ", "))
+		fmt.Fprintf(&out, "}\n\n")
+	}
+	return out.String(), nil
+}
+
+func hclQuote(input string) string {
+	return strings.Replace(strconv.Quote(input), "${", "$${", -1)
+}
+
+func formatOctodns(zone string, recs []Record) (string, error) {
+	var out strings.Builder
+	fmt.Fprintf(&out, "---\n# Record names are relative to zone %s\n", strings.TrimSuffix(zone, "."))
+	for _, set := range Group(recs) {
+		fmt.Fprintf(&out, "%s:\n", strconv.Quote(set.Records[0].relativeName(zone)))
+		fmt.Fprintf(&out, "  type: %s\n", set.Type)
+		fmt.Fprintf(&out, "  ttl: %d\n", set.TTL)
+		switch set.Type {
+		case "CNAME":
+			fmt.Fprintf(&out, "  value: %s\n", set.Records[0].Value)
+		case "CAA":
+			fmt.Fprintf(&out, "  values:\n")
+			for _, r := range set.Records {
+				fmt.Fprintf(&out, "  - flags: %d\n    tag: %s\n    value: %s\n", r.Flag, r.Tag, strconv.Quote(r.Value))
+			}
+		default:
+			return "", fmt.Errorf("Unsupported record type for octodns format: %s", set.Type)
+		}
+	}
+	return out.String(), nil
+}
+
+func formatDnscontrol(zone string, recs []Record) (string, error) {
+	var out strings.Builder
+	fmt.Fprintf(&out, "// Add the following records to D(\"%s\", ...)\n", strings.TrimSuffix(zone, "."))
+	for _, r := range recs {
+		name := r.relativeName(zone)
+		if name == "" {
+			name = "@"
+		}
+		switch r.Type {
+		case "CNAME":
+			fmt.Fprintf(&out, "CNAME(%s, %s, TTL(%d)),\n", strconv.Quote(name), strconv.Quote(r.Value), r.TTL)
+		case "CAA":
+			fmt.Fprintf(&out, "CAA(%s, %s, %s, TTL(%d)),\n", strconv.Quote(name), strconv.Quote(r.Tag), strconv.Quote(r.Value), r.TTL)
+		default:
+			return "", fmt.Errorf("Unsupported record type for dnscontrol format: %s", r.Type)
+		}
+	}
+	return out.String(), nil
+}
+
+type cloudflareCAAData struct {
+	Flags uint8  `json:"flags"`
+	Tag   string `json:"tag"`
+	Value string `json:"value"`
+}
+
+type cloudflareRecord struct {
+	Type    string             `json:"type"`
+	Name    string             `json:"name"`
+	Content string             `json:"content,omitempty"`
+	Data    *cloudflareCAAData `json:"data,omitempty"`
+	TTL     int                `json:"ttl"`
+	Proxied *bool              `json:"proxied,omitempty"`
+}
+
+func formatCloudflare(zone string, recs []Record) (string, error) {
+	cfrecs := make([]cloudflareRecord, 0)
+	for _, r := range recs {
+		cfrec := cloudflareRecord{
+			Type: r.Type,
+			Name: strings.TrimSuffix(r.Name, "."),
+			TTL:  r.TTL,
+		}
+		switch r.Type {
+		case "CNAME":
+			proxied := false
+			cfrec.Content = strings.TrimSuffix(r.Value, ".")
+			cfrec.Proxied = &proxied
+		case "CAA":
+			cfrec.Data = &cloudflareCAAData{Flags: r.Flag, Tag: r.Tag, Value: r.Value}
+		default:
+			return "", fmt.Errorf("Unsupported record type for cloudflare-json format: %s", r.Type)
+		}
+		cfrecs = append(cfrecs, cfrec)
+	}
+	out, err := json.MarshalIndent(cfrecs, "", "  ")
+	return string(out) + "\n", err
+}
+
+type route53ResourceRecord struct {
+	Value string `json:"Value"`
+}
+
+type route53RecordSet struct {
+	Name            string                  `json:"Name"`
+	Type            string                  `json:"Type"`
+	TTL             int                     `json:"TTL"`
+	ResourceRecords []route53ResourceRecord `json:"ResourceRecords"`
+}
+
+type route53Change struct {
+	Action            string           `json:"Action"`
+	ResourceRecordSet route53RecordSet `json:"ResourceRecordSet"`
+}
+
+type route53ChangeBatch struct {
+	Comment string          `json:"Comment"`
+	Changes []route53Change `json:"Changes"`
+}
+
+func formatRoute53(zone string, recs []Record) (string, error) {
+	batch := route53ChangeBatch{
+		Comment: fmt.Sprintf("acme-dns-client records for %s", strings.TrimSuffix(zone, ".")),
+		Changes: make([]route53Change, 0),
+	}
+	for _, set := range Group(recs) {
+		rrset := route53RecordSet{Name: set.Name, Type: set.Type, TTL: set.TTL}
+		for _, r := range set.Records {
+			rrset.ResourceRecords = append(rrset.ResourceRecords, route53ResourceRecord{Value: r.RData()})
+		}
+		batch.Changes = append(batch.Changes, route53Change{Action: "UPSERT", ResourceRecordSet: rrset})
+	}
+	out, err := json.MarshalIndent(batch, "", "  ")
+	return string(out) + "\n", err
+}
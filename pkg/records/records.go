@@ -0,0 +1,145 @@
+package records
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// DefaultTTL is the TTL used for the generated records
+const DefaultTTL = 300
+
+// Record is a single DNS record acme-dns-client asks the user to add to their DNS zone
+type Record struct {
+	// Name is the fully qualified owner name of the record
+	Name string
+	// Type is the record type, either CNAME or CAA
+	Type string
+	TTL  int
+	// Value is the CNAME target or the CAA property value
+	Value string
+	// Flag and Tag are only used for CAA records
+	Flag uint8
+	Tag  string
+}
+
+// NewCNAME returns a CNAME record pointing from name to target
+func NewCNAME(name string, target string) Record {
+	return Record{
+		Name:  dns.Fqdn(name),
+		Type:  "CNAME",
+		TTL:   DefaultTTL,
+		Value: dns.Fqdn(target),
+	}
+}
+
+// NewCAA returns a CAA record for name with the given property tag and value
+func NewCAA(name string, tag string, value string) Record {
+	return Record{
+		Name:  dns.Fqdn(name),
+		Type:  "CAA",
+		TTL:   DefaultTTL,
+		Value: value,
+		Flag:  0,
+		Tag:   tag,
+	}
+}
+
+// ChallengeCNAME returns the CNAME record delegating the ACME challenge of domain to an acme-dns domain
+func ChallengeCNAME(domain string, fulldomain string) Record {
+	return NewCNAME("_acme-challenge."+domain, fulldomain)
+}
+
+// CAAPair returns CAA records with both "issue" and "issuewild" tags for the same value
+func CAAPair(domain string, value string) []Record {
+	return []Record{
+		NewCAA(domain, "issue", value),
+		NewCAA(domain, "issuewild", value),
+	}
+}
+
+// RData returns the presentation format of the record data, eg. `0 issue "letsencrypt.org"` for CAA records
+func (r *Record) RData() string {
+	if r.Type == "CAA" {
+		return fmt.Sprintf("%d %s \"%s\"", r.Flag, r.Tag, r.Value)
+	}
+	return r.Value
+}
+
+// RR converts the record to a miekg/dns resource record
+func (r *Record) RR() (dns.RR, error) {
+	return dns.NewRR(fmt.Sprintf("%s %d IN %s %s", r.Name, r.TTL, r.Type, r.RData()))
+}
+
+// relativeName returns the record name relative to the zone, or an empty string for zone apex
+func (r *Record) relativeName(zone string) string {
+	name := dns.Fqdn(r.Name)
+	zone = dns.Fqdn(zone)
+	if strings.EqualFold(name, zone) {
+		return ""
+	}
+	if dns.IsSubDomain(zone, name) {
+		return strings.TrimSuffix(name, "."+zone)
+	}
+	return name
+}
+
+// RecordSet is a group of records sharing the same name and type
+type RecordSet struct {
+	Name    string
+	Type    string
+	TTL     int
+	Records []Record
+}
+
+// Group groups the records to record sets by name and type, preserving the original order
+func Group(recs []Record) []RecordSet {
+	sets := make([]RecordSet, 0)
+	for _, r := range recs {
+		found := false
+		for i := range sets {
+			if strings.EqualFold(sets[i].Name, r.Name) && sets[i].Type == r.Type {
+				sets[i].Records = append(sets[i].Records, r)
+				found = true
+				break
+			}
+		}
+		if !found {
+			sets = append(sets, RecordSet{Name: r.Name, Type: r.Type, TTL: r.TTL, Records: []Record{r}})
+		}
+	}
+	return sets
+}
+
+// FromRR converts a miekg/dns resource record to a Record
+func FromRR(rr dns.RR) Record {
+	r := Record{
+		Name: dns.Fqdn(rr.Header().Name),
+		Type: dns.TypeToString[rr.Header().Rrtype],
+		TTL:  int(rr.Header().Ttl),
+	}
+	switch v := rr.(type) {
+	case *dns.CNAME:
+		r.Value = dns.Fqdn(v.Target)
+	case *dns.CAA:
+		r.Flag = v.Flag
+		r.Tag = v.Tag
+		r.Value = v.Value
+	default:
+		r.Value = strings.TrimSpace(strings.TrimPrefix(rr.String(), rr.Header().String()))
+	}
+	return r
+}
+
+// Parse parses a record from its owner name, type, TTL and presentation format record data
+func Parse(name string, rtype string, ttl int, rdata string) (Record, error) {
+	rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", dns.Fqdn(name), ttl, rtype, rdata))
+	if err != nil {
+		return Record{}, err
+	}
+	if rr == nil {
+		return Record{}, fmt.Errorf("Empty %s record data for %s", rtype, name)
+	}
+	return FromRR(rr), nil
+}
@@ -0,0 +1,163 @@
+package records
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testCAAValue = "letsencrypt.org; accounturi=https://acme-v02.api.letsencrypt.org/acme/acct/1"
+
+func testRecords() []Record {
+	return append([]Record{ChallengeCNAME("example.org", "abc.auth.example.net")}, CAAPair("example.org", testCAAValue)...)
+}
+
+func TestFormat(t *testing.T) {
+	for _, tc := range []struct {
+		format string
+		want   string
+	}{
+		{"bind", `_acme-challenge.example.org.	300	IN	CNAME	abc.auth.example.net.
+example.org.	300	IN	CAA	0 issue "` + testCAAValue + `"
+example.org.	300	IN	CAA	0 issuewild "` + testCAAValue + `"
+`},
+		{"tinydns", `C_acme-challenge.example.org:abc.auth.example.net:300
+:example.org:257:\000\005issueletsencrypt.org;\040accounturi=https\072//acme-v02.api.letsencrypt.org/acme/acct/1:300
+:example.org:257:\000\011issuewildletsencrypt.org;\040accounturi=https\072//acme-v02.api.letsencrypt.org/acme/acct/1:300
+`},
+		{"terraform", `resource "aws_route53_record" "acme_challenge_example_org_cname" {
+  zone_id = var.zone_id
+  name    = "_acme-challenge.example.org."
+  type    = "CNAME"
+  ttl     = 300
+  records = ["abc.auth.example.net."]
+}
+
+resource "aws_route53_record" "example_org_caa" {
+  zone_id = var.zone_id
+  name    = "example.org."
+  type    = "CAA"
+  ttl     = 300
+  records = ["0 issue \"` + testCAAValue + `\"", "0 issuewild \"` + testCAAValue + `\""]
+}
+
+`},
+		{"octodns", `---
+# Record names are relative to zone example.org
+"_acme-challenge":
+  type: CNAME
+  ttl: 300
+  value: abc.auth.example.net.
+"":
+  type: CAA
+  ttl: 300
+  values:
+  - flags: 0
+    tag: issue
+    value: "` + testCAAValue + `"
+  - flags: 0
+    tag: issuewild
+    value: "` + testCAAValue + `"
+`},
+		{"dnscontrol", `// Add the following records to D("example.org", ...)
+CNAME("_acme-challenge", "abc.auth.example.net.", TTL(300)),
+CAA("@", "issue", "` + testCAAValue + `", TTL(300)),
+CAA("@", "issuewild", "` + testCAAValue + `", TTL(300)),
+`},
+	} {
+		out, err := Format(tc.format, "example.org.", testRecords())
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.format, err)
+		} else if out != tc.want {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tc.format, tc.want, out)
+		}
+	}
+}
+
+func TestFormatCloudflare(t *testing.T) {
+	out, err := Format("cloudflare-json", "example.org", testRecords())
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs := make([]cloudflareRecord, 0)
+	if err = json.Unmarshal([]byte(out), &recs); err != nil {
+		t.Fatalf("Invalid JSON output: %s", err)
+	}
+	if len(recs) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(recs))
+	}
+	cname := recs[0]
+	if cname.Name != "_acme-challenge.example.org" || cname.Content != "abc.auth.example.net" || cname.Proxied == nil || *cname.Proxied {
+		t.Errorf("Unexpected CNAME record: %+v", cname)
+	}
+	if caa := recs[2]; caa.Data == nil || caa.Data.Tag != "issuewild" || caa.Data.Value != testCAAValue || caa.Content != "" {
+		t.Errorf("Unexpected CAA record: %+v", caa)
+	}
+}
+
+func TestFormatRoute53(t *testing.T) {
+	out, err := Format("route53-changebatch", "example.org", testRecords())
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := route53ChangeBatch{}
+	if err = json.Unmarshal([]byte(out), &batch); err != nil {
+		t.Fatalf("Invalid JSON output: %s", err)
+	}
+	// The CAA records are grouped to a single record set, as UPSERT replaces the whole set
+	if len(batch.Changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %d", len(batch.Changes))
+	}
+	caa := batch.Changes[1].ResourceRecordSet
+	if batch.Changes[1].Action != "UPSERT" || caa.Name != "example.org." || len(caa.ResourceRecords) != 2 {
+		t.Errorf("Unexpected CAA change: %+v", batch.Changes[1])
+	}
+	if caa.ResourceRecords[0].Value != `0 issue "`+testCAAValue+`"` {
+		t.Errorf("Unexpected CAA value: %s", caa.ResourceRecords[0].Value)
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	if _, err := Format("zonefile", "example.org", testRecords()); err == nil || !strings.Contains(err.Error(), "bind") {
+		t.Errorf("Expected an unknown format error listing the formats, got: %v", err)
+	}
+	if ValidFormat("zonefile") || !ValidFormat("bind") {
+		t.Error("Unexpected ValidFormat result")
+	}
+	txt, _ := Parse("example.org", "TXT", 300, `"token"`)
+	for _, format := range []string{"tinydns", "octodns", "dnscontrol", "cloudflare-json"} {
+		if _, err := Format(format, "example.org", []Record{txt}); err == nil {
+			t.Errorf("%s: expected an error for an unsupported record type", format)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		rtype string
+		rdata string
+		want  Record
+	}{
+		{"_acme-challenge.example.org", "CNAME", "abc.auth.example.net", ChallengeCNAME("example.org", "abc.auth.example.net")},
+		{"example.org.", "CAA", `0 issue "` + testCAAValue + `"`, NewCAA("example.org", "issue", testCAAValue)},
+		{"example.org", "CAA", `128 iodef "mailto:hostmaster@example.org"`,
+			Record{Name: "example.org.", Type: "CAA", TTL: DefaultTTL, Value: "mailto:hostmaster@example.org", Flag: 128, Tag: "iodef"}},
+	} {
+		r, err := Parse(tc.name, tc.rtype, DefaultTTL, tc.rdata)
+		if err != nil {
+			t.Errorf("%s %s: unexpected error: %s", tc.name, tc.rtype, err)
+			continue
+		}
+		if r != tc.want {
+			t.Errorf("%s %s: expected %+v, got %+v", tc.name, tc.rtype, tc.want, r)
+		}
+		rr, err := r.RR()
+		if err != nil || FromRR(rr) != r {
+			t.Errorf("%s %s: record does not round trip through RR: %v", tc.name, tc.rtype, err)
+		}
+	}
+	if _, err := Parse("example.org", "CAA", DefaultTTL, "not caa"); err == nil {
+		t.Error("Expected an error for invalid record data")
+	}
+}
@@ -0,0 +1,347 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/acme-dns/acme-dns-client/pkg/flock"
+
+	"github.com/cpu/goacmedns"
+)
+
+// SCHEMA_VERSION is the current version of the storage file format
+const SCHEMA_VERSION = 2
+
+// Storage is a goacmedns.Storage that also stores metadata for the accounts
+type Storage interface {
+	goacmedns.Storage
+	// FetchMetadata returns the metadata of the account registered for the domain
+	FetchMetadata(string) (Metadata, error)
+	// PutMetadata sets the metadata of the account registered for the domain. It may not be persisted
+	// until Save is called.
+	PutMetadata(string, Metadata) error
+	// UpdateMetadata changes the metadata of the account registered for the domain with the function, atomically
+	// with respect to other updates. The function may be called more than once, and should only depend on the
+	// metadata passed to it. It may not be persisted until Save is called.
+	UpdateMetadata(string, func(*Metadata)) error
+}
+
+// Metadata holds the information about an acme-dns account that is not part of goacmedns.Account
+type Metadata struct {
+	Registered    *time.Time `json:"registered,omitempty"`
+	Host          string     `json:"host,omitempty"`
+	Server        string     `json:"server,omitempty"`
+	AllowList     []string   `json:"allowlist,omitempty"`
+	Notes         string     `json:"notes,omitempty"`
+	Tags          []string   `json:"tags,omitempty"`
+	LastValidated *time.Time `json:"last_validated,omitempty"`
+	// Validation history, updated on every TXT record update attempt
+	LastAttempt *time.Time `json:"last_attempt,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	Validations int        `json:"validations,omitempty"`
+	Failures    int        `json:"failures,omitempty"`
+}
+
+// Entry is a single account with its metadata in the storage file
+type Entry struct {
+	Account  goacmedns.Account `json:"account"`
+	Metadata Metadata          `json:"metadata"`
+}
+
+// pendingChange holds the changes made to an account since the last Save
+type pendingChange struct {
+	// account is set if the account itself was Put
+	account *goacmedns.Account
+	// metadata holds the metadata changes in the order they were made
+	metadata []func(*Metadata)
+}
+
+// storageFile is the versioned storage file format
+type storageFile struct {
+	Version  int              `json:"version"`
+	Accounts map[string]Entry `json:"accounts"`
+}
+
+// FileStorage implements Storage, persisting the accounts and their metadata to a versioned JSON file.
+// Files in the plain goacmedns.FileStorage format are read as well, and migrated to the current format.
+// Saving takes an advisory lock on the file, applies the changes to the current file contents, and atomically
+// replaces the file while keeping rotating backups of the previous versions. Only the changes made by this
+// instance are applied, so concurrent processes do not overwrite each other's accounts or metadata updates.
+type FileStorage struct {
+	// mu guards the in-memory state, as accounts may be updated from concurrent goroutines
+	mu      sync.Mutex
+	path    string
+	mode    os.FileMode
+	backups int
+	// entries holds all the known accounts, changed the changes made to them since the last Save
+	entries map[string]Entry
+	changed map[string]*pendingChange
+	// accountsChanged is set if accounts, not only their metadata, have changed since the last Save. Backups are
+	// only rotated for these saves, so that the routine validation history updates do not push them out.
+	accountsChanged bool
+	// loadErr is set if the storage file exists but could not be parsed
+	loadErr error
+}
+
+// NewFileStorage returns a new FileStorage instance for the path. The file is created with the provided mode
+// when needed, and up to backups previous versions of it are kept as path.1, path.2 and so on. A storage file
+// in the plain goacmedns format is migrated to the current format right away, the original is kept as a backup.
+func NewFileStorage(path string, mode os.FileMode, backups int) *FileStorage {
+	fs := &FileStorage{
+		path:    path,
+		mode:    mode,
+		backups: backups,
+		changed: make(map[string]*pendingChange),
+	}
+	var version int
+	fs.entries, version, fs.loadErr = fs.read()
+	if fs.loadErr == nil && version < SCHEMA_VERSION && len(fs.entries) > 0 {
+		// Save writes the entries it reads in the current format
+		fs.accountsChanged = true
+		// Migration failures, eg. due to missing write permissions, are retried on the next Save
+		_ = fs.Save()
+	}
+	return fs
+}
+
+// Err returns the error encountered while loading the storage file, if any
+func (f *FileStorage) Err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.loadErr
+}
+
+// read reads and parses the storage file, and returns the entries and the format version of the file.
+// A missing file is treated as empty storage.
+func (f *FileStorage) read() (map[string]Entry, int, error) {
+	entries := make(map[string]Entry)
+	data, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return entries, SCHEMA_VERSION, nil
+	} else if err != nil {
+		return entries, 0, err
+	}
+	versioned := storageFile{}
+	err = json.Unmarshal(data, &versioned)
+	if err == nil && versioned.Version > 0 {
+		if versioned.Version > SCHEMA_VERSION {
+			return entries, versioned.Version, fmt.Errorf("Storage file %s has unsupported version %d", f.path, versioned.Version)
+		}
+		if versioned.Accounts != nil {
+			entries = versioned.Accounts
+		}
+		return entries, versioned.Version, nil
+	}
+	// Plain goacmedns storage format, a map of domains to accounts
+	accounts := make(map[string]goacmedns.Account)
+	err = json.Unmarshal(data, &accounts)
+	if err != nil {
+		return entries, 0, fmt.Errorf("Could not parse storage file %s: %s", f.path, err)
+	}
+	for d, acct := range accounts {
+		entries[d] = Entry{Account: acct, Metadata: Metadata{Server: acct.ServerURL}}
+	}
+	return entries, 1, nil
+}
+
+// Save persists the changes made to the accounts and their metadata since the last Save
+func (f *FileStorage) Save() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lock, err := flock.Acquire(f.path + ".lock")
+	if err != nil {
+		return fmt.Errorf("Could not lock storage file: %s", err)
+	}
+	defer lock.Release()
+
+	// Re-read the file to include changes made by other processes since it was loaded
+	entries, _, err := f.read()
+	if err != nil {
+		return fmt.Errorf("Refusing to overwrite storage file: %s", err)
+	}
+	for d, p := range f.changed {
+		e, exists := entries[d]
+		if p.account != nil {
+			e.Account = *p.account
+		} else if !exists {
+			// Metadata changes are not applied to accounts missing from the file
+			continue
+		}
+		for _, update := range p.metadata {
+			update(&e.Metadata)
+		}
+		entries[d] = e
+	}
+	serialized, err := json.MarshalIndent(storageFile{Version: SCHEMA_VERSION, Accounts: entries}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Failed to marshal accounts: %s", err)
+	}
+	err = f.writeAtomic(serialized, f.accountsChanged)
+	if err != nil {
+		return err
+	}
+	f.entries = entries
+	f.changed = make(map[string]*pendingChange)
+	f.accountsChanged = false
+	f.loadErr = nil
+	return nil
+}
+
+// writeAtomic writes the data to a temporary file, syncs it to disk and renames it over the storage file. The
+// backups are rotated first if rotate is set.
+func (f *FileStorage) writeAtomic(data []byte, rotate bool) error {
+	dir := filepath.Dir(f.path)
+	tmp, err := ioutil.TempFile(dir, filepath.Base(f.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("Failed to create temporary storage file: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(f.mode)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to write temporary storage file: %s", err)
+	}
+	if rotate {
+		err = f.rotateBackups()
+		if err != nil {
+			return fmt.Errorf("Failed to rotate storage backups: %s", err)
+		}
+	}
+	err = os.Rename(tmp.Name(), f.path)
+	if err != nil {
+		return fmt.Errorf("Failed to replace storage file: %s", err)
+	}
+	syncDir(dir)
+	return nil
+}
+
+// rotateBackups shifts the existing backups by one, and copies the current storage file as the first backup
+func (f *FileStorage) rotateBackups() error {
+	if f.backups < 1 {
+		return nil
+	}
+	current, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for i := f.backups - 1; i > 0; i-- {
+		err = os.Rename(backupPath(f.path, i), backupPath(f.path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return ioutil.WriteFile(backupPath(f.path, 1), current, f.mode)
+}
+
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// syncDir syncs the directory entry changes to disk. Errors are ignored, as not all platforms support it.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
+
+// Put adds the account for the domain, keeping the existing metadata. It is persisted on the next Save.
+func (f *FileStorage) Put(domain string, acct goacmedns.Account) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e := f.entries[domain]
+	e.Account = acct
+	f.entries[domain] = e
+	f.pending(domain).account = &acct
+	f.accountsChanged = true
+	return nil
+}
+
+// PutMetadata sets the metadata of an existing account. It is persisted on the next Save.
+func (f *FileStorage) PutMetadata(domain string, md Metadata) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, exists := f.entries[domain]
+	if !exists {
+		return goacmedns.ErrDomainNotFound
+	}
+	e.Metadata = md
+	f.entries[domain] = e
+	p := f.pending(domain)
+	p.metadata = append(p.metadata, func(stored *Metadata) { *stored = md })
+	return nil
+}
+
+// UpdateMetadata changes the metadata of an existing account in place. It is persisted on the next Save, which
+// applies the update again to the metadata read from the file under the lock.
+func (f *FileStorage) UpdateMetadata(domain string, update func(*Metadata)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e, exists := f.entries[domain]
+	if !exists {
+		return goacmedns.ErrDomainNotFound
+	}
+	update(&e.Metadata)
+	f.entries[domain] = e
+	p := f.pending(domain)
+	p.metadata = append(p.metadata, update)
+	return nil
+}
+
+// pending returns the pending changes of the domain, f.mu must be held
+func (f *FileStorage) pending(domain string) *pendingChange {
+	p, exists := f.changed[domain]
+	if !exists {
+		p = &pendingChange{}
+		f.changed[domain] = p
+	}
+	return p
+}
+
+// Fetch returns the account for the domain, or goacmedns.ErrDomainNotFound
+func (f *FileStorage) Fetch(domain string) (goacmedns.Account, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e, exists := f.entries[domain]; exists {
+		return e.Account, nil
+	}
+	return goacmedns.Account{}, goacmedns.ErrDomainNotFound
+}
+
+// FetchMetadata returns the metadata of the account for the domain, or goacmedns.ErrDomainNotFound
+func (f *FileStorage) FetchMetadata(domain string) (Metadata, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if e, exists := f.entries[domain]; exists {
+		return e.Metadata, nil
+	}
+	return Metadata{}, goacmedns.ErrDomainNotFound
+}
+
+// FetchAll returns all the accounts keyed by the domain name
+func (f *FileStorage) FetchAll() map[string]goacmedns.Account {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	accounts := make(map[string]goacmedns.Account)
+	for d, e := range f.entries {
+		accounts[d] = e.Account
+	}
+	return accounts
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cpu/goacmedns"
+)
+
+// storagePath returns a storage file path in a temporary directory removed after the test
+func storagePath(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "acmedns-storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "clientstorage.json")
+}
+
+func testAccount(subdomain string) goacmedns.Account {
+	return goacmedns.Account{
+		FullDomain: subdomain + ".auth.example.net",
+		SubDomain:  subdomain,
+		Username:   subdomain + "-user",
+		Password:   subdomain + "-password",
+		ServerURL:  "https://auth.example.net",
+	}
+}
+
+func countValidation(md *Metadata) {
+	md.Validations++
+}
+
+func TestConcurrentMetadataUpdates(t *testing.T) {
+	path := storagePath(t)
+	setup := NewFileStorage(path, 0600, 0)
+	setup.Put("example.org", testAccount("old"))
+	if err := setup.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both instances load the file before either of them saves
+	first := NewFileStorage(path, 0600, 0)
+	second := NewFileStorage(path, 0600, 0)
+	for _, s := range []*FileStorage{first, second} {
+		if err := s.UpdateMetadata("example.org", countValidation); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The account is registered again while another instance has a metadata update pending
+	if err := first.UpdateMetadata("example.org", countValidation); err != nil {
+		t.Fatal(err)
+	}
+	second.Put("example.org", testAccount("new"))
+	if err := second.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	result := NewFileStorage(path, 0600, 0)
+	acct, err := result.Fetch("example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acct.SubDomain != "new" {
+		t.Errorf("Expected the re-registered account to be kept, got %s", acct.SubDomain)
+	}
+	md, _ := result.FetchMetadata("example.org")
+	if md.Validations != 3 {
+		t.Errorf("Expected 3 validations to be recorded, got %d", md.Validations)
+	}
+}
+
+func TestMetadataUpdateForMissingAccount(t *testing.T) {
+	s := NewFileStorage(storagePath(t), 0600, 0)
+	if err := s.UpdateMetadata("example.org", countValidation); err != goacmedns.ErrDomainNotFound {
+		t.Errorf("Expected ErrDomainNotFound, got %v", err)
+	}
+}
+
+func TestMigration(t *testing.T) {
+	path := storagePath(t)
+	plain := `{"example.org": {"fulldomain": "abc.auth.example.net", "subdomain": "abc", "username": "user",` +
+		` "password": "secret", "server_url": "https://auth.example.net"}}`
+	if err := ioutil.WriteFile(path, []byte(plain), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s := NewFileStorage(path, 0600, 1)
+	if err := s.Err(); err != nil {
+		t.Fatalf("Could not load the goacmedns storage file: %s", err)
+	}
+	_, version, err := s.read()
+	if err != nil || version != SCHEMA_VERSION {
+		t.Errorf("Expected the file to be migrated to version %d, got %d (%v)", SCHEMA_VERSION, version, err)
+	}
+	acct, err := s.Fetch("example.org")
+	if err != nil || acct.Password != "secret" || acct.FullDomain != "abc.auth.example.net" {
+		t.Errorf("Expected the account to be migrated, got %v (%v)", acct, err)
+	}
+	md, _ := s.FetchMetadata("example.org")
+	if md.Server != "https://auth.example.net" {
+		t.Errorf("Expected the server to be filled in the metadata, got %q", md.Server)
+	}
+	backup, _ := ioutil.ReadFile(backupPath(path, 1))
+	if string(backup) != plain {
+		t.Errorf("Expected the original file to be kept as a backup, got %q", backup)
+	}
+}
+
+func TestSaveMergesConcurrentAccounts(t *testing.T) {
+	path := storagePath(t)
+	first := NewFileStorage(path, 0600, 0)
+	second := NewFileStorage(path, 0600, 0)
+	first.Put("example.org", testAccount("first"))
+	second.Put("example.com", testAccount("second"))
+	for _, s := range []*FileStorage{first, second} {
+		if err := s.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	accounts := NewFileStorage(path, 0600, 0).FetchAll()
+	if len(accounts) != 2 || accounts["example.org"].SubDomain != "first" || accounts["example.com"].SubDomain != "second" {
+		t.Errorf("Expected the accounts of both instances to be saved, got %v", accounts)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("Expected the storage file to be saved with mode 0600, got %v (%v)", fi.Mode().Perm(), err)
+	}
+}
+
+func TestSaveRefusesUnparseableFile(t *testing.T) {
+	for _, content := range []string{"{not json", `{"version": 99, "accounts": {}}`} {
+		path := storagePath(t)
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		s := NewFileStorage(path, 0600, 1)
+		if s.Err() == nil {
+			t.Errorf("%q: expected a load error", content)
+		}
+		s.Put("example.org", testAccount("abc"))
+		if err := s.Save(); err == nil {
+			t.Errorf("%q: expected Save to refuse overwriting the file", content)
+		}
+		if data, _ := ioutil.ReadFile(path); string(data) != content {
+			t.Errorf("%q: expected the file to be left untouched, got %q", content, data)
+		}
+	}
+}
+
+func TestBackupRotation(t *testing.T) {
+	path := storagePath(t)
+	s := NewFileStorage(path, 0600, 2)
+	for _, sub := range []string{"one", "two", "three", "four"} {
+		s.Put("example.org", testAccount(sub))
+		if err := s.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Metadata only saves do not rotate the backups
+	s.UpdateMetadata("example.org", countValidation)
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		path      string
+		subdomain string
+	}{
+		{backupPath(path, 1), "three"},
+		{backupPath(path, 2), "two"},
+	} {
+		backup := NewFileStorage(tc.path, 0600, 0)
+		if acct, err := backup.Fetch("example.org"); err != nil || acct.SubDomain != tc.subdomain {
+			t.Errorf("Expected backup %s to hold account %s, got %s (%v)", tc.path, tc.subdomain, acct.SubDomain, err)
+		}
+	}
+	if _, err := os.Stat(backupPath(path, 3)); !os.IsNotExist(err) {
+		t.Errorf("Expected only 2 backups to be kept")
+	}
+	matches, _ := filepath.Glob(path + ".tmp*")
+	if len(matches) > 0 {
+		t.Errorf("Expected the temporary files to be removed, got %v", matches)
+	}
+}